@@ -0,0 +1,627 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"grpc-distributed-fs/dfs"
+)
+
+// 非交互模式的进程退出码
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// 默认分片大小（字节）
+const defaultChunkSize = 512
+
+// 用法错误，退出码为 exitUsage
+var errUsage = errors.New("usage error")
+
+// 子命令的执行函数，返回的结果按 -json 选择输出格式
+type runFunc func(ctx context.Context, sh *Shell, args []string) (any, error)
+
+// 非交互式子命令
+type subcommand struct {
+	usage string
+	setup func(flags *flag.FlagSet) runFunc // 注册子命令专有的参数
+}
+
+var subcommands = map[string]subcommand{
+	"put":       {"put [-r] [-overwrite] [-chunk-size n] [-replicas n] [-write-quorum n] <local-path> [dfs-path]", cliPut},
+	"append":    {"append <local-path> <dfs-path>", noFlags(cliAppend)},
+	"truncate":  {"truncate <dfs-path> <size>", noFlags(cliTruncate)},
+	"pwrite":    {"pwrite <local-path> <dfs-path> <offset>", noFlags(cliWriteAt)},
+	"record":    {"record <dfs-path> [text]", noFlags(cliRecord)},
+	"multipart": {"multipart [-overwrite] [-chunk-size n] [-replicas n] [-write-quorum n] create <dfs-path> | part <upload-id> <part-number> <local-path> | parts <upload-id> | complete <upload-id> [part-number...] | abort <upload-id>", cliMultipart},
+	"defaults":  {"defaults [-chunk-size n] [-replicas n] [-write-quorum n] [dfs-dir]", cliDefaults},
+	"get":       {"get [-r] [-force] <dfs-path> [local-path]", cliGet},
+	"cat":       {"cat <dfs-path>", noFlags(cliCat)},
+	"ls":        {"ls [dfs-path]", noFlags(cliLs)},
+	"rm":        {"rm <dfs-path>", noFlags(cliRm)},
+	"mkdir":     {"mkdir [-p] <dfs-path>", cliMkdir},
+	"stat":      {"stat <dfs-path>", noFlags(cliStat)},
+	"mv":        {"mv <source> <destination>", noFlags(cliMv)},
+}
+
+func noFlags(run runFunc) func(flags *flag.FlagSet) runFunc {
+	return func(flags *flag.FlagSet) runFunc { return run }
+}
+
+// 执行子命令并返回进程退出码
+// 结果输出到 stdout（-json 时为 JSON），进度和错误信息输出到 stderr
+func runSubcommand(sh *Shell, args []string) int {
+	cmd, exists := subcommands[args[0]]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", args[0])
+		printSubcommandUsage()
+		return exitUsage
+	}
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	asJSON := flags.Bool("json", false, "print result as JSON")
+	run := cmd.setup(flags)
+	if err := flags.Parse(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\nUsage: dfs %s\n", err, cmd.usage)
+		return exitUsage
+	}
+
+	result, err := run(context.Background(), sh, flags.Args())
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "Usage: dfs %s\n", cmd.usage)
+		return exitUsage
+	}
+	if err != nil {
+		// 部分失败时仍输出结果，如递归传输的汇总信息
+		switch {
+		case result != nil && *asJSON:
+			printJSON(result)
+		case result != nil:
+			printText(result)
+		case *asJSON:
+			printJSON(map[string]string{"error": err.Error()})
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	switch {
+	case result == nil:
+	case *asJSON:
+		printJSON(result)
+	default:
+		printText(result)
+	}
+	return exitOK
+}
+
+func printSubcommandUsage() {
+	var names []string
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  dfs %s\n", subcommands[name].usage)
+	}
+}
+
+// JSON 输出中的文件信息
+type fileInfoJSON struct {
+	Path             string      `json:"path"`
+	Name             string      `json:"name"`
+	IsDirectory      bool        `json:"is_directory"`
+	Size             int64       `json:"size"`
+	Allocated        int64       `json:"allocated"` // 实际存储的字节数，不含空洞
+	CreationTime     time.Time   `json:"creation_time"`
+	ModificationTime time.Time   `json:"modification_time"`
+	ChunkSize        int64       `json:"chunk_size,omitempty"`
+	Replicas         int         `json:"replicas,omitempty"`
+	WriteQuorum      int         `json:"write_quorum,omitempty"`
+	Chunks           []chunkJSON `json:"chunks,omitempty"`
+}
+
+type chunkJSON struct {
+	ChunkID     string   `json:"chunk_id"`
+	ChunkNumber int      `json:"chunk_number"`
+	Size        int64    `json:"size"`
+	Hole        bool     `json:"hole,omitempty"`
+	Checksum    string   `json:"checksum"`
+	Locations   []string `json:"locations"`
+	Pending     []string `json:"pending,omitempty"`
+}
+
+// 操作结果
+type resultJSON struct {
+	OK      bool   `json:"ok"`
+	Path    string `json:"path"`
+	Message string `json:"-"`
+}
+
+func newFileInfo(entry *dfs.Entry, withChunks bool) fileInfoJSON {
+	info := fileInfoJSON{
+		Path:             entry.Path,
+		Name:             entry.Name,
+		IsDirectory:      entry.IsDirectory,
+		Size:             entry.Size,
+		Allocated:        entry.AllocatedSize(),
+		CreationTime:     entry.CreationTime,
+		ModificationTime: entry.ModificationTime,
+		ChunkSize:        entry.ChunkSize,
+		Replicas:         entry.Replicas,
+		WriteQuorum:      entry.WriteQuorum,
+	}
+	if withChunks {
+		for _, chunk := range entry.Chunks {
+			info.Chunks = append(info.Chunks, chunkJSON{
+				ChunkID:     chunk.ChunkID,
+				ChunkNumber: chunk.ChunkNumber,
+				Size:        chunk.Size,
+				Hole:        chunk.IsHole(),
+				Checksum:    chunk.Checksum,
+				Locations:   chunk.Locations(),
+				Pending:     chunk.Pending,
+			})
+		}
+	}
+	return info
+}
+
+// 追加的记录
+type recordJSON struct {
+	Offset int64 `json:"offset"`
+	Size   int   `json:"size"`
+}
+
+// 分段上传及其已提交的分段
+type multipartJSON struct {
+	UploadID string     `json:"upload_id"`
+	Path     string     `json:"path"`
+	Parts    []partJSON `json:"parts,omitempty"`
+}
+
+type partJSON struct {
+	Number int    `json:"part_number"`
+	ID     string `json:"part_id"`
+	Size   int64  `json:"size"`
+	Chunks int    `json:"chunks"`
+}
+
+func newPart(part dfs.Part) partJSON {
+	return partJSON{Number: part.Number, ID: part.ID, Size: part.Size, Chunks: part.Chunks}
+}
+
+// 目录下新文件实际使用的默认值
+type defaultsJSON struct {
+	Path        string `json:"path"`
+	ChunkSize   int64  `json:"chunk_size"`
+	Replicas    int    `json:"replicas"`
+	WriteQuorum int    `json:"write_quorum"`
+}
+
+// 递归传输的汇总信息
+type treeReportJSON struct {
+	Files    int           `json:"files"`
+	Bytes    int64         `json:"bytes"`
+	Failures []failureJSON `json:"failures"`
+}
+
+type failureJSON struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// 有文件失败时同时返回汇总信息和错误，退出码为 exitFailure
+func newTreeReport(report *dfs.TreeReport, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	result := treeReportJSON{Files: report.Files, Bytes: report.Bytes, Failures: []failureJSON{}}
+	for _, failure := range report.Failures {
+		result.Failures = append(result.Failures, failureJSON{Path: failure.Path, Error: failure.Err.Error()})
+	}
+	if len(result.Failures) > 0 {
+		return result, fmt.Errorf("%d of %d file(s) failed", len(result.Failures), len(result.Failures)+result.Files)
+	}
+	return result, nil
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func printText(v any) {
+	switch v := v.(type) {
+	case resultJSON:
+		fmt.Println(v.Message)
+	case []fileInfoJSON:
+		for _, info := range v {
+			kind := "-"
+			if info.IsDirectory {
+				kind = "d"
+			}
+			modTime := info.ModificationTime
+			if modTime.IsZero() {
+				modTime = info.CreationTime // 目录没有修改时间
+			}
+			fmt.Printf("%s %12d %s %s\n", kind, info.Size, modTime.Local().Format(time.DateTime), info.Name)
+		}
+	case []recordJSON:
+		for _, record := range v {
+			fmt.Printf("Appended %d bytes at offset %d\n", record.Size, record.Offset)
+		}
+	case multipartJSON:
+		fmt.Printf("Multipart upload %s to %s\n", v.UploadID, v.Path)
+		for _, part := range v.Parts {
+			fmt.Printf("Part %d: %d bytes in %d chunk(s)\n", part.Number, part.Size, part.Chunks)
+		}
+	case partJSON:
+		fmt.Printf("Part %d: %d bytes in %d chunk(s)\n", v.Number, v.Size, v.Chunks)
+	case defaultsJSON:
+		fmt.Printf("%s: chunk size %d bytes, %d replica(s), write quorum %d\n", v.Path, v.ChunkSize, v.Replicas, v.WriteQuorum)
+	case treeReportJSON:
+		for _, failure := range v.Failures {
+			fmt.Printf("Failed: %s: %s\n", failure.Path, failure.Error)
+		}
+		fmt.Printf("%d file(s) transferred (%d bytes), %d failed.\n", v.Files, v.Bytes, len(v.Failures))
+	case fileInfoJSON:
+		fmt.Printf("Path:              %s\n", v.Path)
+		fmt.Printf("Directory:         %t\n", v.IsDirectory)
+		fmt.Printf("Size:              %d bytes\n", v.Size)
+		if !v.IsDirectory {
+			fmt.Printf("Allocated:         %d bytes\n", v.Allocated)
+		}
+		fmt.Printf("Creation Time:     %s\n", v.CreationTime.Local().Format(time.RFC3339))
+		fmt.Printf("Modification Time: %s\n", v.ModificationTime.Local().Format(time.RFC3339))
+		if v.ChunkSize > 0 {
+			fmt.Printf("Chunk Size:        %d bytes\n", v.ChunkSize)
+		}
+		if v.Replicas > 0 {
+			fmt.Printf("Replicas:          %d\n", v.Replicas)
+		}
+		if v.WriteQuorum > 0 {
+			fmt.Printf("Write Quorum:      %d\n", v.WriteQuorum)
+		}
+		for _, chunk := range v.Chunks {
+			if chunk.Hole {
+				fmt.Printf("Chunk %d: hole %d bytes\n", chunk.ChunkNumber, chunk.Size)
+				continue
+			}
+			fmt.Printf("Chunk %d: %s %d bytes on %s", chunk.ChunkNumber, chunk.ChunkID, chunk.Size, strings.Join(chunk.Locations, ","))
+			if len(chunk.Pending) > 0 {
+				fmt.Printf(" (pending %s)", strings.Join(chunk.Pending, ","))
+			}
+			fmt.Println()
+		}
+	}
+}
+
+func cliPut(flags *flag.FlagSet) runFunc {
+	var chunkSize sizeFlag
+	flags.Var(&chunkSize, "chunk-size", "chunk size, e.g. 4MiB (default: directory default)")
+	replicas := flags.Int("replicas", 0, "replicas per chunk (default: directory default)")
+	writeQuorum := flags.Int("write-quorum", 0, "replica acks required per chunk (default: directory default)")
+	recursive := flags.Bool("r", false, "upload a directory recursively")
+	overwrite := flags.Bool("overwrite", false, "replace existing files")
+	return func(ctx context.Context, sh *Shell, args []string) (any, error) {
+		if len(args) < 1 || len(args) > 2 || *replicas < 0 || *writeQuorum < 0 {
+			return nil, errUsage
+		}
+		opts := []dfs.PutOption{dfs.WithChunkSize(int64(chunkSize)), dfs.WithReplicas(*replicas), dfs.WithWriteQuorum(*writeQuorum)}
+		if *overwrite {
+			opts = append(opts, dfs.WithReplace())
+		}
+		target := getFileName(strings.TrimRight(args[0], "/"))
+		if len(args) == 2 {
+			target = args[1]
+		}
+		if *recursive {
+			return newTreeReport(sh.PutDir(ctx, args[0], sh.Path(target), opts...))
+		}
+		entry, err := sh.Put(ctx, args[0], sh.Path(target), opts...)
+		if err != nil {
+			return nil, err
+		}
+		return newFileInfo(entry, true), nil
+	}
+}
+
+// 分段上传：create 输出上传 ID，各分段可以在不同机器上以 part 写入，最后 complete 拼接为文件
+func cliMultipart(flags *flag.FlagSet) runFunc {
+	var chunkSize sizeFlag
+	flags.Var(&chunkSize, "chunk-size", "chunk size, e.g. 4MiB (default: directory default)")
+	replicas := flags.Int("replicas", 0, "replicas per chunk (default: directory default)")
+	writeQuorum := flags.Int("write-quorum", 0, "replica acks required per chunk (default: directory default)")
+	overwrite := flags.Bool("overwrite", false, "replace the file if it exists")
+	return func(ctx context.Context, sh *Shell, args []string) (any, error) {
+		if len(args) < 2 || *replicas < 0 || *writeQuorum < 0 {
+			return nil, errUsage
+		}
+		action, args := args[0], args[1:]
+		if action == "create" {
+			if len(args) != 1 {
+				return nil, errUsage
+			}
+			opts := []dfs.PutOption{dfs.WithChunkSize(int64(chunkSize)), dfs.WithReplicas(*replicas), dfs.WithWriteQuorum(*writeQuorum)}
+			if *overwrite {
+				opts = append(opts, dfs.WithReplace())
+			}
+			upload, err := sh.CreateMultipartUpload(ctx, sh.Path(args[0]), opts...)
+			if err != nil {
+				return nil, err
+			}
+			return multipartJSON{UploadID: upload.ID, Path: upload.Path}, nil
+		}
+
+		upload, err := sh.OpenMultipartUpload(ctx, args[0])
+		if err != nil {
+			return nil, err
+		}
+		switch action {
+		case "part":
+			if len(args) != 3 {
+				return nil, errUsage
+			}
+			number, err := strconv.Atoi(args[1])
+			if err != nil {
+				return nil, err
+			}
+			file, err := os.Open(args[2])
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			part, err := upload.UploadPart(ctx, number, file)
+			if err != nil {
+				return nil, err
+			}
+			return newPart(*part), nil
+		case "parts":
+			parts, err := upload.Parts(ctx)
+			if err != nil {
+				return nil, err
+			}
+			result := multipartJSON{UploadID: upload.ID, Path: upload.Path}
+			for _, part := range parts {
+				result.Parts = append(result.Parts, newPart(part))
+			}
+			return result, nil
+		case "complete":
+			var numbers []int
+			for _, arg := range args[1:] {
+				number, err := strconv.Atoi(arg)
+				if err != nil {
+					return nil, err
+				}
+				numbers = append(numbers, number)
+			}
+			entry, err := upload.Complete(ctx, numbers...)
+			if err != nil {
+				return nil, err
+			}
+			return newFileInfo(entry, true), nil
+		case "abort":
+			if len(args) != 1 {
+				return nil, errUsage
+			}
+			if err := upload.Abort(ctx); err != nil {
+				return nil, err
+			}
+			return resultJSON{OK: true, Path: upload.Path, Message: fmt.Sprintf("Aborted multipart upload %s to %s", upload.ID, upload.Path)}, nil
+		}
+		return nil, errUsage
+	}
+}
+
+func cliGet(flags *flag.FlagSet) runFunc {
+	recursive := flags.Bool("r", false, "download a directory recursively")
+	force := flags.Bool("force", false, "overwrite existing local files")
+	return func(ctx context.Context, sh *Shell, args []string) (any, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, errUsage
+		}
+		localPath := getFileName(strings.TrimRight(sh.Path(args[0]), "/"))
+		if len(args) == 2 {
+			localPath = args[1]
+		}
+		var opts []dfs.GetOption
+		if *force {
+			opts = append(opts, dfs.WithOverwrite())
+		}
+		if *recursive {
+			return newTreeReport(sh.GetDir(ctx, sh.Path(args[0]), localPath, opts...))
+		}
+		entry, err := sh.Get(ctx, sh.Path(args[0]), localPath, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return newFileInfo(entry, false), nil
+	}
+}
+
+func cliAppend(ctx context.Context, sh *Shell, args []string) (any, error) {
+	if len(args) != 2 {
+		return nil, errUsage
+	}
+	entry, err := appendFile(ctx, sh, args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	return newFileInfo(entry, false), nil
+}
+
+func cliTruncate(ctx context.Context, sh *Shell, args []string) (any, error) {
+	if len(args) != 2 {
+		return nil, errUsage
+	}
+	size, err := parseLength(args[1])
+	if err != nil {
+		return nil, err
+	}
+	entry, err := sh.Truncate(ctx, sh.Path(args[0]), size)
+	if err != nil {
+		return nil, err
+	}
+	return newFileInfo(entry, false), nil
+}
+
+func cliWriteAt(ctx context.Context, sh *Shell, args []string) (any, error) {
+	if len(args) != 3 {
+		return nil, errUsage
+	}
+	entry, err := writeAt(ctx, sh, args[0], args[1], args[2])
+	if err != nil {
+		return nil, err
+	}
+	return newFileInfo(entry, false), nil
+}
+
+// 以记录追加的方式写入 text 加换行；未指定 text 时标准输入的每一行为一条记录
+func cliRecord(ctx context.Context, sh *Shell, args []string) (any, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errUsage
+	}
+	path := sh.Path(args[0])
+	records := []recordJSON{}
+	appendRecord := func(line string) error {
+		offset, err := sh.AppendRecord(ctx, path, []byte(line+"\n"))
+		if err != nil {
+			return err
+		}
+		records = append(records, recordJSON{Offset: offset, Size: len(line) + 1})
+		return nil
+	}
+	if len(args) == 2 {
+		err := appendRecord(args[1])
+		return records, err
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if err := appendRecord(scanner.Text()); err != nil {
+			return records, err
+		}
+	}
+	return records, scanner.Err()
+}
+
+// 文件内容直接写到 stdout，没有其他输出
+func cliCat(ctx context.Context, sh *Shell, args []string) (any, error) {
+	if len(args) != 1 {
+		return nil, errUsage
+	}
+	return nil, catFile(ctx, sh, args[0], os.Stdout)
+}
+
+func cliLs(ctx context.Context, sh *Shell, args []string) (any, error) {
+	if len(args) > 1 {
+		return nil, errUsage
+	}
+	dir := "/"
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	entries, err := sh.List(ctx, sh.Path(dir))
+	if err != nil {
+		return nil, err
+	}
+	infos := []fileInfoJSON{}
+	for _, entry := range entries {
+		infos = append(infos, newFileInfo(entry, false))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+func cliRm(ctx context.Context, sh *Shell, args []string) (any, error) {
+	if len(args) != 1 {
+		return nil, errUsage
+	}
+	target := sh.Path(args[0])
+	if err := sh.Delete(ctx, target); err != nil {
+		return nil, err
+	}
+	return resultJSON{OK: true, Path: target, Message: "Deleted " + target}, nil
+}
+
+func cliMkdir(flags *flag.FlagSet) runFunc {
+	parents := flags.Bool("p", false, "create parent directories as needed")
+	return func(ctx context.Context, sh *Shell, args []string) (any, error) {
+		if len(args) != 1 {
+			return nil, errUsage
+		}
+		target := sh.Path(args[0])
+		mkdir := sh.Mkdir
+		if *parents {
+			mkdir = sh.MkdirAll
+		}
+		if err := mkdir(ctx, target); err != nil {
+			return nil, err
+		}
+		return resultJSON{OK: true, Path: target, Message: "Created " + target}, nil
+	}
+}
+
+func cliDefaults(flags *flag.FlagSet) runFunc {
+	var chunkSize sizeFlag
+	flags.Var(&chunkSize, "chunk-size", "default chunk size, e.g. 4MiB")
+	replicas := flags.Int("replicas", 0, "default replicas per chunk")
+	writeQuorum := flags.Int("write-quorum", 0, "default replica acks required per chunk")
+	return func(ctx context.Context, sh *Shell, args []string) (any, error) {
+		if len(args) > 1 || *replicas < 0 || *writeQuorum < 0 {
+			return nil, errUsage
+		}
+		dir := "/"
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		dir = sh.Path(dir)
+		if chunkSize > 0 || *replicas > 0 || *writeQuorum > 0 {
+			if err := setDefaults(ctx, sh, dir, dfs.Defaults{ChunkSize: int64(chunkSize), Replicas: *replicas, WriteQuorum: *writeQuorum}); err != nil {
+				return nil, err
+			}
+		}
+		defaults, err := sh.Defaults(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		return defaultsJSON{Path: dir, ChunkSize: defaults.ChunkSize, Replicas: defaults.Replicas, WriteQuorum: defaults.WriteQuorum}, nil
+	}
+}
+
+func cliStat(ctx context.Context, sh *Shell, args []string) (any, error) {
+	if len(args) != 1 {
+		return nil, errUsage
+	}
+	entry, err := sh.Stat(ctx, sh.Path(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	return newFileInfo(entry, true), nil
+}
+
+func cliMv(ctx context.Context, sh *Shell, args []string) (any, error) {
+	if len(args) != 2 {
+		return nil, errUsage
+	}
+	from, to := sh.Path(args[0]), sh.Path(args[1])
+	if err := sh.Rename(ctx, from, to); err != nil {
+		return nil, err
+	}
+	return resultJSON{OK: true, Path: to, Message: fmt.Sprintf("Moved %s to %s", from, to)}, nil
+}
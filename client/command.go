@@ -2,237 +2,444 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"os"
 	"strings"
 	"time"
 
-	"grpc-distributed-fs/metadata"
-	pb "grpc-distributed-fs/proto/fs"
+	"grpc-distributed-fs/dfs"
 
 	"github.com/davecgh/go-spew/spew"
 )
 
-type Client struct {
-	pb.FileSystemClient
-}
-
-func UploadFile(clients [](*Client), tree *metadata.FileTree, command []string, chunkSize int64) {
-	if len(command) < 2 {
-		fmt.Println("Usage: upload <local-file-path>")
+func UploadFile(sh *Shell, command []string) {
+	flags := flag.NewFlagSet("upload", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "upload a directory recursively")
+	var chunkSize sizeFlag
+	flags.Var(&chunkSize, "chunk-size", "chunk size, e.g. 4MiB (default: directory default)")
+	replicas := flags.Int("replicas", 0, "replicas per chunk (default: directory default)")
+	writeQuorum := flags.Int("write-quorum", 0, "replica acks required per chunk (default: directory default)")
+	overwrite := flags.Bool("overwrite", false, "replace existing files")
+	if err := flags.Parse(command[1:]); err != nil || flags.NArg() < 1 {
+		fmt.Println("Usage: upload [-r] [--overwrite] [--chunk-size n] [--replicas n] [--write-quorum n] <local-path>")
+		return
+	}
+	localPath := flags.Arg(0)
+	target := sh.Path(getFileName(strings.TrimRight(localPath, "/")))
+	opts := []dfs.PutOption{dfs.WithChunkSize(int64(chunkSize)), dfs.WithReplicas(*replicas), dfs.WithWriteQuorum(*writeQuorum)}
+	if *overwrite {
+		opts = append(opts, dfs.WithReplace())
+	}
+	if *recursive {
+		report, err := sh.PutDir(context.Background(), localPath, target, opts...)
+		printTreeReport("uploaded", report, err)
 		return
 	}
-	localPath := command[1]
-	data, err := ioutil.ReadFile(localPath)
+	entry, err := sh.Put(context.Background(), localPath, target, opts...)
 	if err != nil {
-		fmt.Printf("Failed to read local file: %v\n", err)
+		fmt.Println("Error:", err)
 		return
 	}
+	fmt.Printf("File '%s' uploaded successfully.\n", entry.Name)
+}
 
-	filename := getFileName(localPath)
-	parentPath := tree.Current.Metadata.Name
-	fileID := parentPath + filename // 生成唯一文件标识符
-	var fileChunks []metadata.FileChunk
-
-	// 分片逻辑
-	for i := int64(0); i < int64(len(data)); i += chunkSize {
-		end := i + chunkSize
-		if end > int64(len(data)) {
-			end = int64(len(data))
-		}
+func DownloadFile(sh *Shell, command []string) {
+	flags := flag.NewFlagSet("download", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "download a directory recursively")
+	force := flags.Bool("force", false, "overwrite existing local files")
+	if err := flags.Parse(command[1:]); err != nil || flags.NArg() < 1 || flags.NArg() > 2 {
+		fmt.Println("Usage: download [-r] [--force] <dfs-path> [local-path]")
+		return
+	}
+	filename := flags.Arg(0)
+	localPath := getFileName(strings.TrimRight(sh.Path(filename), "/"))
+	if flags.NArg() > 1 {
+		localPath = flags.Arg(1)
+	}
+	var opts []dfs.GetOption
+	if *force {
+		opts = append(opts, dfs.WithOverwrite())
+	}
+	if *recursive {
+		report, err := sh.GetDir(context.Background(), sh.Path(filename), localPath, opts...)
+		printTreeReport("downloaded", report, err)
+		return
+	}
+	if _, err := sh.Get(context.Background(), sh.Path(filename), localPath, opts...); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("File '%s' downloaded successfully.\n", filename)
+}
 
-		chunkData := data[i:end]
-		chunkID := fileID + string(int(i/chunkSize)) // 唯一分片标识符
-
-		// 分片副本的存储位置
-		storageLocation1 := int(i) % len(clients)                 // 第一个存储节点
-		storageLocation2 := (storageLocation1 + 1) % len(clients) // 第二个存储节点
-
-		// 创建FileChunk对象
-		fileChunk := metadata.FileChunk{
-			ChunkID:         chunkID,
-			FileID:          fileID,
-			ChunkNumber:     int(i / chunkSize),
-			OriginalName:    filename,
-			Size:            int64(len(chunkData)),
-			StorageLocation: storageLocation1,                              // 存储位置1
-			Replicas:        []string{fmt.Sprintf("%d", storageLocation2)}, // 存储位置2作为副本
-		}
+// 将文件内容输出到 stdout
+func CatFile(sh *Shell, command []string) {
+	if len(command) < 2 {
+		fmt.Println("Usage: cat <file-name>")
+		return
+	}
+	if err := catFile(context.Background(), sh, command[1], os.Stdout); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
 
-		// 上传分片到两个节点
-		for _, storageLocation := range []int{storageLocation1, storageLocation2} {
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-			defer cancel()
-
-			_, err = clients[storageLocation].WriteFile(ctx, &pb.WriteRequest{
-				Filename: chunkID,
-				Data:     chunkData,
-			})
-			if err != nil {
-				fmt.Printf("Failed to upload chunk %d to node %d: %v\n", i/chunkSize, storageLocation, err)
-				return
-			}
-			fmt.Printf("Uploaded chunk %d to node %d successfully.\n", i/chunkSize, storageLocation)
-		}
+// 按顺序读取分片并写入 w，分片读到时才获取
+func catFile(ctx context.Context, sh *Shell, name string, w io.Writer) error {
+	f, err := sh.Open(ctx, sh.Path(name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
 
-		fileChunks = append(fileChunks, fileChunk)
+// 输出递归传输的汇总信息
+func printTreeReport(verb string, report *dfs.TreeReport, err error) {
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
 	}
+	for _, failure := range report.Failures {
+		fmt.Printf("Failed: %s: %v\n", failure.Path, failure.Err)
+	}
+	fmt.Printf("%d file(s) %s (%d bytes), %d failed.\n", report.Files, verb, report.Bytes, len(report.Failures))
+}
 
-	// 更新元数据到文件树
-	err = tree.AddFile(&metadata.FileMetadata{
-		Name:             filename,
-		IsDirectory:      false,
-		Size:             int64(len(data)),
-		CreationTime:     time.Now(),
-		ModificationTime: time.Now(),
-		Chunks:           fileChunks, // 记录所有分片
-	})
+// 将本地文件的内容追加到 DFS 文件末尾
+func AppendFile(sh *Shell, command []string) {
+	if len(command) != 3 {
+		fmt.Println("Usage: append <local-file> <dfs-file>")
+		return
+	}
+	entry, err := appendFile(context.Background(), sh, command[1], command[2])
 	if err != nil {
-		fmt.Printf("Failed to update metadata: %v\n", err)
+		fmt.Println("Error:", err)
 		return
 	}
+	fmt.Printf("Appended to '%s', now %d bytes.\n", entry.Path, entry.Size)
+}
 
-	fmt.Printf("File '%s' uploaded successfully.\n", filename)
+func appendFile(ctx context.Context, sh *Shell, localPath, name string) (*dfs.Entry, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return sh.Append(ctx, sh.Path(name), f)
 }
 
-func DownloadFile(clients [](*Client), tree *metadata.FileTree, command []string) {
-	if len(command) < 2 {
-		fmt.Println("Usage: download <file-name>")
+// 将 DFS 文件截断或补零到指定大小
+func TruncateFile(sh *Shell, command []string) {
+	if len(command) != 3 {
+		fmt.Println("Usage: truncate <dfs-file> <size>")
 		return
 	}
-	filename := command[1]
-
-	// 获取文件元数据
-	fileMetadata, err := tree.GetFileMetadata(filename)
+	size, err := parseLength(command[2])
 	if err != nil {
-		fmt.Printf("Failed to find file metadata: %v\n", err)
+		fmt.Println("Error:", err)
 		return
 	}
-
-	if fileMetadata.IsDirectory {
-		fmt.Println("Cannot download a directory.")
+	entry, err := sh.Truncate(context.Background(), sh.Path(command[1]), size)
+	if err != nil {
+		fmt.Println("Error:", err)
 		return
 	}
+	fmt.Printf("Truncated '%s' to %d bytes.\n", entry.Path, entry.Size)
+}
 
-	var fileData []byte
-	for _, chunk := range fileMetadata.Chunks {
-		// 下载分片
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-		defer cancel()
-
-		clientIndex := chunk.StorageLocation % len(clients)
-		replicasIndex := (clientIndex + 1) % len(clients)
-		resp, err := clients[clientIndex].ReadFile(ctx, &pb.ReadRequest{Filename: chunk.ChunkID})
-		if err != nil {
-			fmt.Printf("Failed to download chunk %d and test get replicas: %v\n", chunk.ChunkNumber, err)
-			resp, err = clients[replicasIndex].ReadFile(ctx, &pb.ReadRequest{Filename: chunk.ChunkID})
-			if err != nil {
-				return
-			}
-		}
+// 将本地文件的内容写入 DFS 文件的指定偏移处，只重写受影响的分片
+func WriteAtOffset(sh *Shell, command []string) {
+	if len(command) != 4 {
+		fmt.Println("Usage: pwrite <local-file> <dfs-file> <offset>")
+		return
+	}
+	entry, err := writeAt(context.Background(), sh, command[1], command[2], command[3])
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("Wrote '%s' at offset %s, now %d bytes.\n", entry.Path, command[3], entry.Size)
+}
 
-		fmt.Printf("Downloaded chunk %d successfully.\n", chunk.ChunkNumber)
-		fileData = append(fileData, resp.Data...)
+func writeAt(ctx context.Context, sh *Shell, localPath, name, offset string) (*dfs.Entry, error) {
+	off, err := parseLength(offset)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return nil, err
 	}
+	return sh.WriteAt(ctx, sh.Path(name), data, off)
+}
 
-	// 将拼接后的数据保存到本地
-	err = ioutil.WriteFile(filename, fileData, 0644)
+// 以记录追加的方式写入一行文本，与其他客户端的追加互不交错
+func AppendRecord(sh *Shell, command []string) {
+	if len(command) < 3 {
+		fmt.Println("Usage: record <dfs-file> <text>")
+		return
+	}
+	record := strings.Join(command[2:], " ") + "\n"
+	offset, err := sh.AppendRecord(context.Background(), sh.Path(command[1]), []byte(record))
 	if err != nil {
-		fmt.Printf("Failed to save file locally: %v\n", err)
+		fmt.Println("Error:", err)
 		return
 	}
-
-	fmt.Printf("File '%s' downloaded successfully.\n", filename)
+	fmt.Printf("Appended %d bytes at offset %d.\n", len(record), offset)
 }
 
-func RemoveFile(clients [](*Client), tree *metadata.FileTree, command []string) {
+func RemoveFile(sh *Shell, command []string) {
 	if len(command) < 2 {
 		fmt.Println("Usage: rm <file-name>")
 		return
 	}
 	filename := command[1]
+	if err := sh.Delete(context.Background(), sh.Path(filename)); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("File '%s' deleted successfully.\n", filename)
+}
 
-	// 获取文件元数据
-	fileMetadata, err := tree.GetFileMetadata(filename)
+// 获取路径租约，持有期间其他客户端不能修改该路径，直到 unlock 或退出
+func LockPath(sh *Shell, command []string) {
+	flags := flag.NewFlagSet("lock", flag.ContinueOnError)
+	shared := flags.Bool("shared", false, "take a shared lease (other readers may also hold one)")
+	if err := flags.Parse(command[1:]); err != nil || flags.NArg() != 1 {
+		fmt.Println("Usage: lock [-shared] <dfs-path>")
+		return
+	}
+	lock, err := sh.Lock(context.Background(), sh.Path(flags.Arg(0)), !*shared)
 	if err != nil {
-		fmt.Printf("Failed to find file metadata: %v\n", err)
+		fmt.Println("Error:", err)
 		return
 	}
+	for _, holder := range lock.Holders {
+		mode := "exclusive"
+		if !holder.Exclusive {
+			mode = "shared"
+		}
+		fmt.Printf("%s: %s lease held by %s, expires in %v\n", lock.Path, mode, holder.Owner, time.Until(holder.ExpiresAt).Round(time.Second))
+	}
+}
 
-	if fileMetadata.IsDirectory {
-		fmt.Println("Cannot remove a directory with this function.")
+// 释放由 lock 取得的路径租约
+func UnlockPath(sh *Shell, command []string) {
+	if len(command) != 2 {
+		fmt.Println("Usage: unlock <dfs-path>")
 		return
 	}
+	if err := sh.Unlock(context.Background(), sh.Path(command[1])); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("Lease on '%s' released.\n", sh.Path(command[1]))
+}
 
-	// 删除所有分片
-	for _, chunk := range fileMetadata.Chunks {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-		defer cancel()
-
-		clientIndex := chunk.StorageLocation % len(clients)
-		_, err := clients[clientIndex].DeleteFile(ctx, &pb.DeleteRequest{Filename: chunk.ChunkID})
-		if err != nil {
-			fmt.Printf("Failed to delete chunk %d: %v\n", chunk.ChunkNumber, err)
-			return
-		}
+// 工具函数
+func getFileName(path string) string {
+	parts := strings.Split(path, "/")
+	return parts[len(parts)-1]
+}
 
-		fmt.Printf("Deleted chunk %d successfully.\n", chunk.ChunkNumber)
+// 列出副本未跨越不同故障域的分片
+func AuditPlacement(sh *Shell) {
+	violations, err := sh.Client.AuditPlacement(context.Background())
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
 	}
+	for _, v := range violations {
+		fmt.Printf("%s chunk %d (%s): nodes %v share failure domain %s\n", v.Path, v.Chunk.ChunkNumber, v.Chunk.ChunkID, v.Nodes, v.Domain)
+	}
+	fmt.Printf("Audit finished: %d violation(s).\n", len(violations))
+}
 
-	// 更新元数据
-	err = tree.RemoveFile(filename)
+// 列出块报告发现副本丢失的分片
+func ListMissingReplicas(sh *Shell) {
+	missing, err := sh.MissingReplicas(context.Background())
 	if err != nil {
-		fmt.Printf("Failed to update metadata: %v\n", err)
+		fmt.Println("Error:", err)
 		return
 	}
+	for _, m := range missing {
+		fmt.Printf("%s chunk %d (%s): lost on %v, live on %v\n", m.Path, m.Chunk.ChunkNumber, m.Chunk.ChunkID, m.Lost, m.Chunk.Locations())
+	}
+	fmt.Printf("%d chunk(s) with missing replicas.\n", len(missing))
+}
 
-	fmt.Printf("File '%s' deleted successfully.\n", filename)
+// 列出未提交的上传
+func ListUploads(sh *Shell) {
+	uploads, err := sh.Uploads(context.Background())
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, u := range uploads {
+		if u.Multipart {
+			fmt.Printf("%s (%s): multipart, %d part(s) committed, %d chunk(s) recorded, expires in %v\n", u.Path, u.ID, len(u.Parts), len(u.Chunks), time.Until(u.ExpiresAt).Round(time.Second))
+			continue
+		}
+		fmt.Printf("%s (%s): %d chunk(s) recorded, expires in %v\n", u.Path, u.ID, len(u.Chunks), time.Until(u.ExpiresAt).Round(time.Second))
+	}
+	fmt.Printf("%d uncommitted upload(s).\n", len(uploads))
 }
 
-// 工具函数
-func getFileName(path string) string {
-	parts := strings.Split(path, "/")
-	return parts[len(parts)-1]
+// 回收元数据中不再引用的分片
+func CollectGarbage(sh *Shell, command []string) {
+	flags := flag.NewFlagSet("gc", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only report orphan chunks")
+	grace := flags.Duration("grace", time.Hour, "minimum age of orphan chunks to delete")
+	if err := flags.Parse(command[1:]); err != nil {
+		fmt.Println("Usage: gc [-dry-run] [-grace <duration>]")
+		return
+	}
+
+	report, err := sh.Client.CollectGarbage(context.Background(), dfs.GCOptions{DryRun: *dryRun, Grace: *grace})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for node, err := range report.NodeErrors {
+		fmt.Printf("Failed to list chunks on node %s: %v\n", node, err)
+	}
+	for _, orphan := range report.Orphans {
+		age := "unknown age"
+		if !orphan.ModTime.IsZero() {
+			age = time.Since(orphan.ModTime).Round(time.Second).String() + " old"
+		}
+		switch {
+		case *dryRun:
+			fmt.Printf("Orphan chunk %s on node %s (%d bytes, %s)\n", orphan.ChunkID, orphan.Node, orphan.Size, age)
+		case orphan.Err != nil:
+			fmt.Printf("Failed to delete orphan chunk %s on node %s: %v\n", orphan.ChunkID, orphan.Node, orphan.Err)
+		default:
+			fmt.Printf("Deleted orphan chunk %s on node %s (%d bytes, %s)\n", orphan.ChunkID, orphan.Node, orphan.Size, age)
+		}
+	}
+
+	if *dryRun {
+		fmt.Printf("GC dry run: %d orphan chunk(s), %d bytes.\n", len(report.Orphans), report.OrphanBytes)
+		return
+	}
+	fmt.Printf("GC finished: deleted %d of %d orphan chunk(s).\n", report.Deleted, len(report.Orphans))
 }
 
 // 查看文件元数据
-func ViewMetadata(tree *metadata.FileTree, command []string) {
+func ViewMetadata(sh *Shell, command []string) {
 	if len(command) < 2 {
 		fmt.Println("Usage: meta <file-name>")
 		return
 	}
 	filename := command[1]
-	meta, err := tree.GetFileMetadata(filename)
+	entry, err := sh.Stat(context.Background(), sh.Path(filename))
 	if err != nil {
-		fmt.Printf("File not found: %v\n", err)
+		fmt.Println("Error:", err)
 		return
 	}
-	spew.Dump(meta)
+	spew.Dump(entry.FileMetadata)
+	if !entry.IsDirectory {
+		fmt.Printf("Allocated: %d of %d bytes\n", entry.AllocatedSize(), entry.Size)
+	}
 	// fmt.Printf("Metadata for '%s':\n", filename)
 	// fmt.Printf("  Size: %d bytes\n", meta.Size)
 	// fmt.Printf("  Creation Time: %s\n", meta.CreationTime)
 	// fmt.Printf("  Modification Time: %s\n", meta.ModificationTime)
 }
 
+// 查看或设置目录下新文件的默认分片大小、副本数和写入法定数
+func DirectoryDefaults(sh *Shell, command []string) {
+	flags := flag.NewFlagSet("defaults", flag.ContinueOnError)
+	var chunkSize sizeFlag
+	flags.Var(&chunkSize, "chunk-size", "default chunk size, e.g. 4MiB")
+	replicas := flags.Int("replicas", 0, "default replicas per chunk")
+	writeQuorum := flags.Int("write-quorum", 0, "default replica acks required per chunk")
+	if err := flags.Parse(command[1:]); err != nil || flags.NArg() > 1 {
+		fmt.Println("Usage: defaults [--chunk-size n] [--replicas n] [--write-quorum n] [directory]")
+		return
+	}
+	dir := sh.Path(flags.Arg(0))
+	ctx := context.Background()
+	if chunkSize > 0 || *replicas > 0 || *writeQuorum > 0 {
+		if err := setDefaults(ctx, sh, dir, dfs.Defaults{ChunkSize: int64(chunkSize), Replicas: *replicas, WriteQuorum: *writeQuorum}); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	}
+	defaults, err := sh.Defaults(ctx, dir)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("%s: chunk size %d bytes, %d replica(s), write quorum %d\n", dir, defaults.ChunkSize, defaults.Replicas, defaults.WriteQuorum)
+}
+
+// 只修改指定的默认值，其余项保留目录上已设置的值
+func setDefaults(ctx context.Context, sh *Shell, dir string, defaults dfs.Defaults) error {
+	entry, err := sh.Stat(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if defaults.ChunkSize == 0 {
+		defaults.ChunkSize = entry.ChunkSize
+	}
+	if defaults.Replicas == 0 {
+		defaults.Replicas = entry.Replicas
+	}
+	if defaults.WriteQuorum == 0 {
+		defaults.WriteQuorum = entry.WriteQuorum
+	}
+	return sh.SetDefaults(ctx, dir, defaults)
+}
+
+// 列出当前目录
+func ListDirectory(sh *Shell) {
+	entries, err := sh.List(context.Background(), sh.Cwd())
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	fmt.Println("Contents:", names)
+}
+
 // 切换目录
-func ChangeDirectory(tree *metadata.FileTree, command []string) {
+func ChangeDirectory(sh *Shell, command []string) {
 	if len(command) < 2 {
 		fmt.Println("Usage: cd <directory>")
 		return
 	}
-	err := tree.Cd(command[1])
+	err := sh.Cd(context.Background(), command[1])
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+// 重命名或移动
+func MoveFile(sh *Shell, command []string) {
+	if len(command) < 3 {
+		fmt.Println("Usage: mv <source> <destination>")
+		return
+	}
+	err := sh.Rename(context.Background(), sh.Path(command[1]), sh.Path(command[2]))
 	if err != nil {
 		fmt.Println("Error:", err)
 	}
 }
 
 // 创建目录
-func MakeDirectory(tree *metadata.FileTree, command []string) {
+func MakeDirectory(sh *Shell, command []string) {
 	if len(command) < 2 {
 		fmt.Println("Usage: mkdir <directory>")
 		return
 	}
-	err := tree.Mkdir(command[1])
+	err := sh.Mkdir(context.Background(), sh.Path(command[1]))
 	if err != nil {
 		fmt.Println("Error:", err)
 	}
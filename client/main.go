@@ -2,41 +2,71 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 
-	"grpc-distributed-fs/metadata"
-	pb "grpc-distributed-fs/proto/fs"
-
-	"google.golang.org/grpc"
+	"grpc-distributed-fs/dfs"
 )
 
-// 初始化 gRPC 客户端
-func NewClient(conn *grpc.ClientConn) *Client {
-	return &Client{pb.NewFileSystemClient(conn)}
-}
-func NewConn(port string) *Client {
-	conn, err := grpc.Dial(port, grpc.WithInsecure())
+func main() {
+	metaAddr := flag.String("meta", dfs.DefaultMetaAddr, "metadata service address")
+	clusterConfig := flag.String("cluster", "", "storage node config file (JSON)")
+	domainLevel := flag.String("domain", dfs.DefaultDomainLevel, "failure domain level replicas must span: zone, rack or host")
+	parallel := flag.Int("parallel", 8, "max concurrent chunk transfers")
+	nodeParallel := flag.Int("node-parallel", 2, "max concurrent chunk transfers per storage node")
+	hedgeDelay := flag.Duration("hedge-delay", dfs.DefaultHedgeDelay, "send a hedged read to the next replica after this delay (negative disables)")
+	rpcTimeout := flag.Duration("rpc-timeout", dfs.DefaultRPCTimeout, "timeout per RPC attempt, extended for chunk transfers by chunk size")
+	maxAttempts := flag.Int("max-attempts", dfs.DefaultRetryPolicy.MaxAttempts, "max attempts per operation on retryable errors (1 disables retries)")
+	breakerThreshold := flag.Int("breaker-threshold", dfs.DefaultBreakerPolicy.Threshold, "consecutive failures before requests to a storage node are paused (negative disables)")
+	owner := flag.String("owner", "", "lease owner name shown to other clients (default user@host:pid)")
+	lockTTL := flag.Duration("lock-ttl", dfs.DefaultLockTTL, "path lease duration, renewed while held")
+	flag.Parse()
+
+	cfg := dfs.Config{
+		MetaAddr:     *metaAddr,
+		DomainLevel:  *domainLevel,
+		ChunkSize:    defaultChunkSize,
+		Parallel:     max(*parallel, 1),
+		NodeParallel: max(*nodeParallel, 1),
+		HedgeDelay:   *hedgeDelay,
+		RPCTimeout:   *rpcTimeout,
+		Retry:        dfs.RetryPolicy{MaxAttempts: *maxAttempts},
+		Breaker:      dfs.BreakerPolicy{Threshold: *breakerThreshold},
+		Owner:        *owner,
+		LockTTL:      *lockTTL,
+	}
+	if *clusterConfig != "" {
+		var err error
+		cfg.Nodes, err = dfs.LoadNodeConfigs(*clusterConfig)
+		if err != nil {
+			log.Fatalf("Failed to load cluster config: %v", err)
+		}
+	}
+
+	// 分片级进度信息输出到 stderr，不与 cat 等命令输出到 stdout 的文件内容混在一起
+	cfg.Progress = os.Stderr
+
+	client, err := dfs.New(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect: %v", err)
+		log.Fatalf("Failed to init client: %v", err)
 	}
-	client := NewClient(conn)
-	return client
-}
-func main() {
-	clients := make([](*Client), 3)
-	clients[0] = NewConn(":50051")
-	clients[1] = NewConn(":50052")
-	clients[2] = NewConn(":50053")
+	defer client.Close()
+	sh := NewShell(client)
 
-	tree := metadata.NewFileTree() // 初始化文件树
+	// 带子命令时以非交互模式执行，如 dfs put a.txt /docs/
+	if flag.NArg() > 0 {
+		code := runSubcommand(sh, flag.Args())
+		client.Close()
+		os.Exit(code)
+	}
 
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Println("Welcome to the Distributed File System!")
 	for {
-		fmt.Printf("%s> ", tree.Current.Metadata.Name)
+		fmt.Printf("%s> ", sh.Cwd())
 		input, _ := reader.ReadString('\n')
 		command := strings.Fields(strings.TrimSpace(input))
 		if len(command) == 0 {
@@ -45,19 +75,45 @@ func main() {
 
 		switch command[0] {
 		case "ls":
-			fmt.Println("Contents:", tree.Ls())
+			ListDirectory(sh)
 		case "cd":
-			ChangeDirectory(tree, command)
+			ChangeDirectory(sh, command)
 		case "mkdir":
-			MakeDirectory(tree, command)
+			MakeDirectory(sh, command)
 		case "upload":
-			UploadFile(clients, tree, command, 512)
+			UploadFile(sh, command)
 		case "download":
-			DownloadFile(clients, tree, command)
+			DownloadFile(sh, command)
+		case "cat":
+			CatFile(sh, command)
+		case "append":
+			AppendFile(sh, command)
+		case "truncate":
+			TruncateFile(sh, command)
+		case "pwrite":
+			WriteAtOffset(sh, command)
+		case "record":
+			AppendRecord(sh, command)
 		case "rm":
-			RemoveFile(clients, tree, command)
+			RemoveFile(sh, command)
+		case "mv":
+			MoveFile(sh, command)
+		case "lock":
+			LockPath(sh, command)
+		case "unlock":
+			UnlockPath(sh, command)
 		case "meta":
-			ViewMetadata(tree, command)
+			ViewMetadata(sh, command)
+		case "audit":
+			AuditPlacement(sh)
+		case "missing":
+			ListMissingReplicas(sh)
+		case "uploads":
+			ListUploads(sh)
+		case "defaults":
+			DirectoryDefaults(sh, command)
+		case "gc":
+			CollectGarbage(sh, command)
 		case "exit":
 			fmt.Println("Exiting...")
 			return
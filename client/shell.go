@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path"
+
+	"grpc-distributed-fs/dfs"
+	"grpc-distributed-fs/metadata"
+)
+
+// 命令行会话：客户端及当前目录，相对路径按当前目录解析
+type Shell struct {
+	*dfs.Client
+	cwd string
+}
+
+func NewShell(client *dfs.Client) *Shell {
+	return &Shell{Client: client, cwd: "/"}
+}
+
+// 当前目录
+func (s *Shell) Cwd() string {
+	return s.cwd
+}
+
+// 将相对路径解析为绝对路径
+func (s *Shell) Path(name string) string {
+	if path.IsAbs(name) {
+		return metadata.CleanPath(name)
+	}
+	return metadata.CleanPath(path.Join(s.cwd, name))
+}
+
+// 切换目录
+func (s *Shell) Cd(ctx context.Context, name string) error {
+	if name == ".." && s.cwd == "/" {
+		return errors.New("already at root")
+	}
+	target := s.Path(name)
+	entry, err := s.Stat(ctx, target)
+	if err != nil {
+		return err
+	}
+	if !entry.IsDirectory {
+		return errors.New(target + ": " + dfs.ErrNotDir.Error())
+	}
+	s.cwd = target
+	return nil
+}
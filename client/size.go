@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 大小单位，K、M、G 与 KiB、MiB、GiB 相同，按 1024 进位
+var sizeUnits = []struct {
+	suffix string
+	scale  int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// 解析带单位的大小，如 512、4K、64MiB
+func parseSize(s string) (int64, error) {
+	n, err := parseLength(s)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n, nil
+}
+
+// 同 parseSize，但允许 0，用于文件长度
+func parseLength(s string) (int64, error) {
+	scale := int64(1)
+	number := s
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(strings.ToUpper(s), strings.ToUpper(unit.suffix)) && len(s) > len(unit.suffix) {
+			scale = unit.scale
+			number = s[:len(s)-len(unit.suffix)]
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(number), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if n > math.MaxInt64/scale {
+		return 0, fmt.Errorf("size %q out of range", s)
+	}
+	return n * scale, nil
+}
+
+// 带单位的大小参数，实现 flag.Value
+type sizeFlag int64
+
+func (f *sizeFlag) String() string {
+	return strconv.FormatInt(int64(*f), 10)
+}
+
+func (f *sizeFlag) Set(s string) error {
+	n, err := parseSize(s)
+	if err != nil {
+		return err
+	}
+	*f = sizeFlag(n)
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+		zero    bool // 大小为 0：parseSize 拒绝，parseLength 接受
+	}{
+		{in: "512", want: 512},
+		{in: "4K", want: 4 << 10},
+		{in: "4k", want: 4 << 10},
+		{in: "4KiB", want: 4 << 10},
+		{in: "4kib", want: 4 << 10},
+		{in: "64M", want: 64 << 20},
+		{in: "64MiB", want: 64 << 20},
+		{in: "64mib", want: 64 << 20},
+		{in: "2G", want: 2 << 30},
+		{in: "2GiB", want: 2 << 30},
+		{in: "1KB", want: 1000},
+		{in: "1mb", want: 1000 * 1000},
+		{in: "1GB", want: 1000 * 1000 * 1000},
+		{in: "100B", want: 100},
+		{in: "4 K", want: 4 << 10},
+		{in: "0", zero: true},
+		{in: "0K", zero: true},
+		{in: "-1", wantErr: true},
+		{in: "-4K", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "K", wantErr: true},
+		{in: "MiB", wantErr: true},
+		{in: "4X", wantErr: true},
+		{in: "1.5M", wantErr: true},
+		{in: "9223372036854775807", want: math.MaxInt64},
+		{in: "9223372036854775808", wantErr: true},
+		{in: "8589934591G", want: 8589934591 << 30},
+		{in: "8589934592G", wantErr: true},
+		{in: "9007199254740992K", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseSize(tt.in)
+			if (err != nil) != (tt.wantErr || tt.zero) || got != tt.want {
+				t.Errorf("parseSize(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr || tt.zero)
+			}
+			got, err = parseLength(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseLength(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package dfs
+
+import (
+	"context"
+	"sort"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+)
+
+// 副本未跨越不同故障域的分片
+type PlacementViolation struct {
+	Path   string
+	Chunk  metadata.FileChunk
+	Domain string   // 被多个副本共享的故障域
+	Nodes  []string // 共享该故障域的节点
+}
+
+// 列出副本未跨越不同故障域的分片
+func (c *Client) AuditPlacement(ctx context.Context) ([]PlacementViolation, error) {
+	var violations []PlacementViolation
+	err := c.Walk(ctx, "/", func(entry *Entry) error {
+		for _, chunk := range entry.Chunks {
+			conflicts := c.cluster.placement.Conflicts(chunk.Locations())
+			var domains []string
+			for domain := range conflicts {
+				domains = append(domains, domain)
+			}
+			sort.Strings(domains)
+			for _, domain := range domains {
+				violations = append(violations, PlacementViolation{Path: entry.Path, Chunk: chunk, Domain: domain, Nodes: conflicts[domain]})
+			}
+		}
+		return nil
+	})
+	return violations, err
+}
+
+// 块报告发现副本丢失的分片
+type MissingReplica struct {
+	Path  string
+	Chunk metadata.FileChunk // 仍存活的副本位置
+	Lost  []string           // 副本已丢失的节点
+}
+
+// 列出块报告发现副本丢失的分片
+func (c *Client) MissingReplicas(ctx context.Context) ([]MissingReplica, error) {
+	var resp *metapb.MissingReplicasResponse
+	err := c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+		resp, err = c.meta.MissingReplicas(ctx, &metapb.Empty{})
+		return err
+	})
+	if err != nil {
+		return nil, pathError("missing", "/", err)
+	}
+	var missing []MissingReplica
+	for _, replica := range resp.Replicas {
+		missing = append(missing, MissingReplica{
+			Path:  replica.Path,
+			Chunk: metadata.FileChunkFromProto(replica.Chunk),
+			Lost:  replica.Lost,
+		})
+	}
+	return missing, nil
+}
@@ -0,0 +1,328 @@
+// Package dfs 是分布式文件系统的 Go 客户端库。
+//
+// 路径均为元数据服务上的绝对路径，如 /docs/a.txt，相对路径按根目录解析。
+package dfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// 默认配置
+const (
+	DefaultMetaAddr    = ":50050"
+	DefaultDomainLevel = "host"
+	DefaultReplicas    = 2   // 每个分片保存的副本数
+	DefaultChunkSize   = 512 // 分片大小（字节）
+	DefaultHedgeDelay  = 200 * time.Millisecond
+	DefaultRPCTimeout  = time.Second
+	DefaultThroughput  = 4 << 20 // 估算分片传输超时时间时假定的最低吞吐量（字节/秒）
+	DefaultLockTTL     = 30 * time.Second
+)
+
+// 客户端配置，零值字段使用默认配置
+type Config struct {
+	MetaAddr     string        // 元数据服务地址
+	Nodes        []NodeConfig  // 存储节点
+	DomainLevel  string        // 副本需跨越的故障域层级：zone、rack 或 host
+	Replicas     int           // 每个分片保存的副本数，目录未设置默认值时使用
+	WriteQuorum  int           // 写入成功所需的副本确认数，目录未设置默认值时使用，0 表示多数副本
+	ChunkSize    int64         // 分片大小（字节），目录未设置默认值时使用
+	Parallel     int           // 整体分片传输并发数
+	NodeParallel int           // 单节点分片传输并发数
+	HedgeDelay   time.Duration // 读取分片超过该时间未返回时向下一个副本发出对冲请求，负数表示不对冲
+	RPCTimeout   time.Duration // 单次 RPC 尝试的超时时间，分片传输在此基础上按分片大小延长
+	Throughput   int64         // 按不低于该吞吐量（字节/秒）估算分片传输的超时时间
+	Retry        RetryPolicy   // 可重试错误的重试策略
+	Breaker      BreakerPolicy // 存储节点熔断策略
+	Progress     io.Writer     // 分片级进度信息的输出位置，nil 时不输出
+	Owner        string        // 路径租约的持有者名称，默认为 用户@主机:进程号
+	LockTTL      time.Duration // 路径租约的有效期，持有期间每隔三分之一有效期续期
+}
+
+// 分布式文件系统客户端，可被多个 goroutine 同时使用
+type Client struct {
+	meta        metapb.MetadataClient
+	metaConn    *grpc.ClientConn
+	cluster     *cluster
+	chunkSize   int64
+	replicas    int
+	writeQuorum int
+	hedgeDelay  time.Duration
+	progress    io.Writer
+
+	leaseMu sync.Mutex
+	leases  map[string]*metapb.AppendLease // 路径 -> 缓存的追加租约
+
+	owner   string
+	lockTTL time.Duration
+	locksMu sync.Mutex
+	locks   map[string]*heldLock // 路径 -> 本客户端持有的路径租约
+}
+
+// 目录树中的一个条目
+type Entry struct {
+	Path string
+	*metadata.FileMetadata
+}
+
+// 连接元数据服务和所有存储节点
+func New(cfg Config) (*Client, error) {
+	if cfg.MetaAddr == "" {
+		cfg.MetaAddr = DefaultMetaAddr
+	}
+	if cfg.Nodes == nil {
+		cfg.Nodes = DefaultNodes
+	}
+	if cfg.DomainLevel == "" {
+		cfg.DomainLevel = DefaultDomainLevel
+	}
+	if cfg.Replicas <= 0 {
+		cfg.Replicas = DefaultReplicas
+	}
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = DefaultChunkSize
+	}
+	if cfg.HedgeDelay == 0 {
+		cfg.HedgeDelay = DefaultHedgeDelay
+	}
+	if cfg.Parallel <= 0 {
+		cfg.Parallel = defaultParallel
+	}
+	if cfg.NodeParallel <= 0 {
+		cfg.NodeParallel = defaultNodeParallel
+	}
+	if cfg.RPCTimeout <= 0 {
+		cfg.RPCTimeout = DefaultRPCTimeout
+	}
+	if cfg.Throughput <= 0 {
+		cfg.Throughput = DefaultThroughput
+	}
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if cfg.Retry.InitialBackoff <= 0 {
+		cfg.Retry.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+	if cfg.Retry.MaxBackoff <= 0 {
+		cfg.Retry.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	if cfg.Breaker.Threshold == 0 {
+		cfg.Breaker.Threshold = DefaultBreakerPolicy.Threshold
+	}
+	if cfg.Breaker.Cooldown <= 0 {
+		cfg.Breaker.Cooldown = DefaultBreakerPolicy.Cooldown
+	}
+	if cfg.Owner == "" {
+		cfg.Owner = defaultOwner()
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = DefaultLockTTL
+	}
+
+	retry := &retrier{RetryPolicy: cfg.Retry, rpcTimeout: cfg.RPCTimeout, minThroughput: cfg.Throughput}
+	cluster, err := newCluster(cfg.Nodes, cfg.DomainLevel, cfg.Replicas, retry, cfg.Breaker)
+	if err != nil {
+		return nil, err
+	}
+	cluster.parallel = cfg.Parallel
+	cluster.nodeParallel = cfg.NodeParallel
+
+	conn, err := grpc.NewClient(cfg.MetaAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), retry.connectParams(),
+		grpc.WithUnaryInterceptor(ownerInterceptor(cfg.Owner)))
+	if err != nil {
+		cluster.close()
+		return nil, fmt.Errorf("connect metadata service: %w", err)
+	}
+	return &Client{
+		meta:        metapb.NewMetadataClient(conn),
+		metaConn:    conn,
+		cluster:     cluster,
+		chunkSize:   cfg.ChunkSize,
+		replicas:    cfg.Replicas,
+		writeQuorum: max(cfg.WriteQuorum, 0),
+		hedgeDelay:  cfg.HedgeDelay,
+		progress:    cfg.Progress,
+		leases:      make(map[string]*metapb.AppendLease),
+		owner:       cfg.Owner,
+		lockTTL:     cfg.LockTTL,
+		locks:       make(map[string]*heldLock),
+	}, nil
+}
+
+// 释放持有的路径租约并关闭所有连接
+func (c *Client) Close() error {
+	c.releaseLocks()
+	c.cluster.close()
+	return c.metaConn.Close()
+}
+
+// 输出分片级进度信息
+func (c *Client) logf(format string, args ...any) {
+	if c.progress != nil {
+		fmt.Fprintf(c.progress, format, args...)
+	}
+}
+
+// 获取文件或目录的元数据
+func (c *Client) Stat(ctx context.Context, path string) (*Entry, error) {
+	path = metadata.CleanPath(path)
+	var resp *metapb.FileMetadata
+	err := c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+		resp, err = c.meta.Stat(ctx, &metapb.PathRequest{Path: path})
+		return err
+	})
+	if err != nil {
+		return nil, pathError("stat", path, err)
+	}
+	return &Entry{Path: path, FileMetadata: metadata.FileMetadataFromProto(resp)}, nil
+}
+
+// 列出目录下的所有条目
+func (c *Client) List(ctx context.Context, dir string) ([]*Entry, error) {
+	dir = metadata.CleanPath(dir)
+	var resp *metapb.ListResponse
+	err := c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+		resp, err = c.meta.List(ctx, &metapb.PathRequest{Path: dir})
+		return err
+	})
+	if err != nil {
+		return nil, pathError("list", dir, err)
+	}
+	var entries []*Entry
+	for _, entry := range resp.Entries {
+		meta := metadata.FileMetadataFromProto(entry)
+		entries = append(entries, &Entry{Path: joinPath(dir, meta.Name), FileMetadata: meta})
+	}
+	return entries, nil
+}
+
+// 创建目录，父目录必须已存在
+func (c *Client) Mkdir(ctx context.Context, path string) error {
+	path = metadata.CleanPath(path)
+	err := c.callMeta(ctx, false, func(ctx context.Context) error {
+		_, err := c.meta.Mkdir(ctx, &metapb.PathRequest{Path: path})
+		return err
+	})
+	return pathError("mkdir", path, err)
+}
+
+// 逐级创建目录，已存在的目录跳过
+func (c *Client) MkdirAll(ctx context.Context, path string) error {
+	path = metadata.CleanPath(path)
+	if path == "/" {
+		return nil
+	}
+	entry, err := c.Stat(ctx, path)
+	if err == nil {
+		if !entry.IsDirectory {
+			return &fs.PathError{Op: "mkdir", Path: path, Err: ErrNotDir}
+		}
+		return nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	dir, _ := metadata.SplitPath(path)
+	if err := c.MkdirAll(ctx, dir); err != nil {
+		return err
+	}
+	return c.Mkdir(ctx, path)
+}
+
+// 重命名或移动，to 为已存在的目录时移动到该目录下
+func (c *Client) Rename(ctx context.Context, from, to string) error {
+	from, to = metadata.CleanPath(from), metadata.CleanPath(to)
+	unlock, err := c.lockForWrite(ctx, "rename", from)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	err = c.callMeta(ctx, false, func(ctx context.Context) error {
+		_, err := c.meta.Rename(ctx, &metapb.RenameRequest{From: from, To: to})
+		return err
+	})
+	return pathError("rename", from, err)
+}
+
+// 删除文件的元数据及其全部分片
+// 先在排他租约下删除元数据，之后尽力删除各副本，删除失败的副本成为孤儿，由垃圾回收清理
+func (c *Client) Delete(ctx context.Context, path string) error {
+	path = metadata.CleanPath(path)
+	// 取得排他租约后再读取分片列表，避免删除其他客户端刚写入的文件
+	unlock, err := c.lockForWrite(ctx, "remove", path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entry, err := c.Stat(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := c.removeEntry(ctx, entry.Path); err != nil {
+		return err
+	}
+
+	// 删除所有分片的全部副本
+	for _, chunk := range entry.Chunks {
+		if chunk.IsHole() {
+			continue
+		}
+		for _, nodeID := range chunk.Locations() {
+			err := c.cluster.call(ctx, nodeID, c.cluster.retry.rpcTimeout, func(ctx context.Context, node *storageNode) error {
+				_, err := node.DeleteFile(ctx, &pb.DeleteRequest{Filename: chunk.ChunkID})
+				return err
+			})
+			if err != nil {
+				c.logf("Failed to delete chunk %d from node %s: %v\n", chunk.ChunkNumber, nodeID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// 删除元数据中的文件或空目录
+func (c *Client) removeEntry(ctx context.Context, path string) error {
+	err := c.callMeta(ctx, false, func(ctx context.Context) error {
+		_, err := c.meta.RemoveFile(ctx, &metapb.PathRequest{Path: path})
+		return err
+	})
+	return pathError("remove", path, err)
+}
+
+// 遍历 root 及其下的所有条目
+func (c *Client) Walk(ctx context.Context, root string, fn func(entry *Entry) error) error {
+	root = metadata.CleanPath(root)
+	stream, err := c.meta.Walk(ctx, &metapb.PathRequest{Path: root})
+	if err != nil {
+		return pathError("walk", root, err)
+	}
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return pathError("walk", root, err)
+		}
+		if err := fn(&Entry{Path: entry.Path, FileMetadata: metadata.FileMetadataFromProto(entry.Metadata)}); err != nil {
+			return err
+		}
+	}
+}
+
+func joinPath(dir, name string) string {
+	return metadata.CleanPath(dir + "/" + name)
+}
@@ -0,0 +1,162 @@
+package dfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"grpc-distributed-fs/placement"
+	pb "grpc-distributed-fs/proto/fs"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// 存储节点配置
+type NodeConfig struct {
+	ID     string            `json:"id"`
+	Addr   string            `json:"addr"`
+	Weight float64           `json:"weight"` // 节点容量权重，缺省为 1
+	Labels map[string]string `json:"labels"` // 拓扑标签，节点自报的标签优先
+}
+
+// 未指定节点时使用的默认节点
+var DefaultNodes = []NodeConfig{
+	{ID: "node1", Addr: ":50051", Weight: 1},
+	{ID: "node2", Addr: ":50052", Weight: 1},
+	{ID: "node3", Addr: ":50053", Weight: 1},
+}
+
+// 从 JSON 文件读取节点配置
+func LoadNodeConfigs(path string) ([]NodeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []NodeConfig
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// 读取分片时单条响应的最大字节数，分片整块返回，需大于最大分片大小
+const maxChunkMessage = math.MaxInt32
+
+// 存储节点
+type storageNode struct {
+	NodeConfig
+	pb.FileSystemClient
+	conn    *grpc.ClientConn
+	breaker *breaker
+}
+
+// 集群：存储节点及分片放置策略
+type cluster struct {
+	nodes        map[string]*storageNode
+	placement    *placement.DomainAware
+	replicas     int
+	parallel     int // 整体分片传输并发数
+	nodeParallel int // 单节点分片传输并发数
+	retry        *retrier
+}
+
+// 连接所有存储节点并初始化放置策略，副本分布在 level 层级互不相同的故障域中
+func newCluster(configs []NodeConfig, level string, replicas int, retry *retrier, breakerPolicy BreakerPolicy) (*cluster, error) {
+	if !slices.Contains(placement.Levels, level) {
+		return nil, fmt.Errorf("unknown failure domain level %q", level)
+	}
+	c := &cluster{
+		nodes:    make(map[string]*storageNode),
+		replicas: replicas,
+		retry:    retry,
+	}
+	var nodes []placement.Node
+	for _, cfg := range configs {
+		if _, exists := c.nodes[cfg.ID]; exists {
+			c.close()
+			return nil, fmt.Errorf("duplicate node id %q", cfg.ID)
+		}
+		if cfg.Weight == 0 {
+			cfg.Weight = 1
+		}
+		conn, err := grpc.NewClient(cfg.Addr,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			retry.connectParams(),
+			grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxChunkMessage)))
+		if err != nil {
+			c.close()
+			return nil, fmt.Errorf("connect node %s: %w", cfg.ID, err)
+		}
+		node := &storageNode{
+			NodeConfig:       cfg,
+			FileSystemClient: pb.NewFileSystemClient(conn),
+			conn:             conn,
+			breaker:          &breaker{BreakerPolicy: breakerPolicy, node: cfg.ID},
+		}
+		node.fetchLabels()
+		c.nodes[cfg.ID] = node
+		nodes = append(nodes, placement.Node{ID: cfg.ID, Weight: cfg.Weight, Labels: node.Labels})
+	}
+	if len(nodes) < c.replicas {
+		c.close()
+		return nil, fmt.Errorf("need at least %d storage nodes, got %d", c.replicas, len(nodes))
+	}
+	c.placement = placement.NewDomainAware(nodes, level)
+	return c, nil
+}
+
+func (c *cluster) close() {
+	for _, node := range c.nodes {
+		node.conn.Close()
+	}
+}
+
+// 按 ID 查找存储节点
+func (c *cluster) node(id string) (*storageNode, error) {
+	node, exists := c.nodes[id]
+	if !exists {
+		return nil, fmt.Errorf("unknown storage node %q", id)
+	}
+	return node, nil
+}
+
+// 按 ID 排序的节点列表
+func (c *cluster) sortedNodes() []*storageNode {
+	nodes := make([]*storageNode, 0, len(c.nodes))
+	for _, node := range c.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// 向节点查询其自报的拓扑标签并覆盖配置中的同名标签
+// 节点不可达时沿用配置中的标签
+func (n *storageNode) fetchLabels() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	info, err := n.NodeInfo(ctx, &pb.NodeInfoRequest{})
+	if err != nil {
+		log.Printf("Failed to fetch labels from node %s: %v", n.ID, err)
+		return
+	}
+	if info.Id != "" && info.Id != n.ID {
+		log.Printf("Node %s reports id %q", n.ID, info.Id)
+	}
+	labels := make(map[string]string)
+	for key, value := range n.Labels {
+		labels[key] = value
+	}
+	for key, value := range info.Labels {
+		labels[key] = value
+	}
+	n.Labels = labels
+}
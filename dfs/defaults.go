@@ -0,0 +1,137 @@
+package dfs
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+)
+
+// 上传选项
+type PutOption func(*putOptions)
+
+type putOptions struct {
+	chunkSize   int64
+	replicas    int
+	writeQuorum int
+	replace     bool
+	multipart   bool // 由 CreateMultipartUpload 设置
+}
+
+// 目标文件已存在时以新内容替换，不指定时返回 fs.ErrExist
+// 新内容提交时原子地替换原文件，原文件的分片随后被回收
+func WithReplace() PutOption {
+	return func(o *putOptions) {
+		o.replace = true
+	}
+}
+
+// 指定本次上传的分片大小（字节），不指定时依次使用目录默认值和 Config.ChunkSize
+func WithChunkSize(size int64) PutOption {
+	return func(o *putOptions) {
+		if size > 0 {
+			o.chunkSize = size
+		}
+	}
+}
+
+// 指定本次上传每个分片的副本数，不指定时依次使用目录默认值和 Config.Replicas
+func WithReplicas(n int) PutOption {
+	return func(o *putOptions) {
+		if n > 0 {
+			o.replicas = n
+		}
+	}
+}
+
+// 指定本次上传每个分片写入成功所需的副本确认数，其余副本由元数据服务在后台修复
+// 不指定时依次使用目录默认值、Config.WriteQuorum 和多数副本
+func WithWriteQuorum(w int) PutOption {
+	return func(o *putOptions) {
+		if w > 0 {
+			o.writeQuorum = w
+		}
+	}
+}
+
+// 合并上传选项：显式指定的值优先，其次是目录 dir 的默认值，最后是客户端配置
+func (c *Client) putOptions(ctx context.Context, dir string, opts []PutOption) (putOptions, error) {
+	var options putOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.chunkSize == 0 || options.replicas == 0 || options.writeQuorum == 0 {
+		stored, err := c.storedDefaults(ctx, dir)
+		if err != nil {
+			return options, err
+		}
+		if options.chunkSize == 0 {
+			options.chunkSize = cmp.Or(stored.ChunkSize, c.chunkSize)
+		}
+		if options.replicas == 0 {
+			options.replicas = cmp.Or(stored.Replicas, c.replicas)
+		}
+		// 继承的写入法定数不超过本次的副本数
+		if options.writeQuorum == 0 {
+			options.writeQuorum = min(cmp.Or(stored.WriteQuorum, c.writeQuorum, majority(options.replicas)), options.replicas)
+		}
+	}
+	if options.replicas > len(c.cluster.nodes) {
+		return options, fmt.Errorf("%w: %d replicas requested, cluster has %d nodes", ErrNotEnoughNodes, options.replicas, len(c.cluster.nodes))
+	}
+	if options.writeQuorum > options.replicas {
+		return options, fmt.Errorf("write quorum %d exceeds %d replicas", options.writeQuorum, options.replicas)
+	}
+	return options, nil
+}
+
+// n 个副本的多数
+func majority(n int) int {
+	return n/2 + 1
+}
+
+// 目录下新文件的分片大小、副本数和写入法定数，与元数据服务保存的默认值相同
+type Defaults = metadata.Defaults
+
+// 目录 dir 下新文件实际使用的默认值：沿上级目录继承，都未设置时使用客户端配置
+func (c *Client) Defaults(ctx context.Context, dir string) (Defaults, error) {
+	defaults, err := c.storedDefaults(ctx, dir)
+	if err != nil {
+		return defaults, err
+	}
+	defaults.ChunkSize = cmp.Or(defaults.ChunkSize, c.chunkSize)
+	defaults.Replicas = cmp.Or(defaults.Replicas, c.replicas)
+	defaults.WriteQuorum = min(cmp.Or(defaults.WriteQuorum, c.writeQuorum, majority(defaults.Replicas)), defaults.Replicas)
+	return defaults, nil
+}
+
+// 目录 dir 沿上级目录继承的默认值，未设置的字段为 0
+func (c *Client) storedDefaults(ctx context.Context, dir string) (Defaults, error) {
+	dir = metadata.CleanPath(dir)
+	var resp *metapb.Defaults
+	err := c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+		resp, err = c.meta.GetDefaults(ctx, &metapb.PathRequest{Path: dir})
+		return err
+	})
+	if err != nil {
+		return Defaults{}, pathError("defaults", dir, err)
+	}
+	return Defaults{ChunkSize: resp.ChunkSize, Replicas: int(resp.Replicas), WriteQuorum: int(resp.WriteQuorum)}, nil
+}
+
+// 设置目录 dir 下新文件的默认值，由其下的子目录继承，字段为 0 表示沿用上级目录
+func (c *Client) SetDefaults(ctx context.Context, dir string, defaults Defaults) error {
+	dir = metadata.CleanPath(dir)
+	err := c.callMeta(ctx, true, func(ctx context.Context) error {
+		_, err := c.meta.SetDefaults(ctx, &metapb.SetDefaultsRequest{
+			Path:        dir,
+			ChunkSize:   defaults.ChunkSize,
+			Replicas:    int32(defaults.Replicas),
+			WriteQuorum: int32(defaults.WriteQuorum),
+		})
+		return err
+	})
+	return pathError("defaults", dir, err)
+}
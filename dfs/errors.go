@@ -0,0 +1,95 @@
+package dfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"grpc-distributed-fs/metadata"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 客户端返回的错误
+// 目录树操作的错误为 *fs.PathError，可以用 errors.Is 判断 fs.ErrNotExist、fs.ErrExist 以及下列错误
+var (
+	ErrNotDir         = errors.New("not a directory")
+	ErrIsDir          = errors.New("is a directory")
+	ErrNotEmpty       = errors.New("directory not empty")
+	ErrNotEnoughNodes = errors.New("not enough storage nodes in distinct failure domains")
+	ErrWriteQuorum    = errors.New("write quorum not reached")
+	ErrCircuitOpen    = errors.New("storage node unavailable, requests paused")
+	ErrUploadExpired  = errors.New("upload expired or aborted")
+	ErrConflict       = errors.New("file changed by another writer")
+	ErrRecordTooLarge = errors.New("record larger than chunk size")
+	ErrLocked         = errors.New("locked by another client")
+	ErrNotLocked      = errors.New("not locked by this client")
+)
+
+// 路径被其他客户端的租约锁定，errors.Is(err, ErrLocked) 成立
+type LockedError struct {
+	Holders []string // 持有冲突租约的客户端
+}
+
+func (e *LockedError) Error() string {
+	return "locked by " + strings.Join(e.Holders, ", ")
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// 分片传输失败
+type ChunkError struct {
+	Op          string // upload、download 或 delete
+	ChunkNumber int
+	Nodes       []string // 尝试过的节点
+	Err         error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("%s chunk %d on nodes %v: %v", e.Op, e.ChunkNumber, e.Nodes, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// 将元数据服务返回的 gRPC 错误转换为 *fs.PathError
+func pathError(op, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return &fs.PathError{Op: op, Path: path, Err: err}
+	}
+	switch st.Code() {
+	case codes.NotFound:
+		err = fs.ErrNotExist
+	case codes.AlreadyExists:
+		err = fs.ErrExist
+	case codes.FailedPrecondition:
+		switch st.Message() {
+		case metadata.ErrNotDirectory.Error():
+			err = ErrNotDir
+		case metadata.ErrNotEmpty.Error():
+			err = ErrNotEmpty
+		case metadata.ErrIsDirectory.Error():
+			err = ErrIsDir
+		default:
+			if holders, locked := strings.CutPrefix(st.Message(), "locked by "); locked {
+				err = &LockedError{Holders: strings.Split(holders, ", ")}
+			} else {
+				err = errors.New(st.Message())
+			}
+		}
+	case codes.Aborted:
+		err = ErrConflict
+	default:
+		err = errors.New(st.Message())
+	}
+	return &fs.PathError{Op: op, Path: path, Err: err}
+}
@@ -0,0 +1,388 @@
+package dfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"sync"
+
+	"grpc-distributed-fs/metadata"
+)
+
+var (
+	_ io.ReadSeekCloser = (*File)(nil)
+	_ io.ReaderAt       = (*File)(nil)
+	_ io.WriteCloser    = (*Writer)(nil)
+)
+
+// 以只读方式打开的文件，实现 io.ReadSeekCloser 和 io.ReaderAt
+// 分片在读到时才从存储节点获取，只缓存最近读取的一个分片
+type File struct {
+	c       *Client
+	ctx     context.Context
+	entry   *Entry
+	offsets []int64 // 各分片在文件中的起始偏移
+
+	mu     sync.Mutex
+	offset int64 // Read 和 Seek 使用的当前偏移
+	cached int   // 缓存的分片编号，-1 表示无缓存
+	data   []byte
+	closed bool
+}
+
+// 打开文件用于读取
+func (c *Client) Open(ctx context.Context, path string) (*File, error) {
+	entry, err := c.Stat(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDirectory {
+		return nil, &fs.PathError{Op: "open", Path: entry.Path, Err: ErrIsDir}
+	}
+	f := &File{c: c, ctx: ctx, entry: entry, cached: -1}
+	var offset int64
+	for _, chunk := range entry.Chunks {
+		f.offsets = append(f.offsets, offset)
+		offset += chunk.Size
+	}
+	return f, nil
+}
+
+// 文件的元数据
+func (f *File) Stat() *Entry {
+	return f.entry
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.readAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.entry.Path, Err: errors.New("negative offset")}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.readAt(p, off)
+	if err == nil && n < len(p) {
+		err = io.EOF // ReadAt 读不满时必须返回错误
+	}
+	return n, err
+}
+
+// 从 off 处读取数据，跨分片时逐个分片读取，调用方需持有锁
+func (f *File) readAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if off >= f.entry.Size {
+		return 0, io.EOF
+	}
+	n := 0
+	for n < len(p) && off < f.entry.Size {
+		// 找到包含 off 的分片
+		i := sort.Search(len(f.offsets), func(i int) bool { return f.offsets[i] > off }) - 1
+		if chunk := f.entry.Chunks[i]; chunk.IsHole() {
+			// 空洞读出零，不获取数据
+			zeros := min(int64(len(p)-n), f.offsets[i]+chunk.Size-off)
+			clear(p[n : n+int(zeros)])
+			n += int(zeros)
+			off += zeros
+			continue
+		}
+		data, err := f.chunk(i)
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], data[off-f.offsets[i]:])
+		n += copied
+		off += int64(copied)
+	}
+	return n, nil
+}
+
+// 获取第 i 个分片的数据，调用方需持有锁
+func (f *File) chunk(i int) ([]byte, error) {
+	if f.cached == i {
+		return f.data, nil
+	}
+	data, err := f.c.fetchChunk(f.ctx, f.entry.Chunks[i])
+	if err != nil {
+		return nil, err
+	}
+	f.cached, f.data = i, data
+	return data, nil
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.entry.Size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.entry.Path, Err: errors.New("invalid whence")}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.entry.Path, Err: errors.New("negative position")}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	f.data = nil
+	return nil
+}
+
+// 以写入方式创建的文件，实现 io.WriteCloser
+// 写入的数据每满一个分片就上传并登记，Close 时上传剩余数据并提交，提交前文件不可见
+// 更新已有文件时，写入的数据接在保留的前 keep 个分片之后，提交时替换原文件其余的分片
+// 写入分段上传的一个分段时，Close 只提交该分段
+type Writer struct {
+	c        *Client
+	ctx      context.Context
+	path     string
+	fileID   string // 分片标识符的前缀
+	uploadID string // 登记分片的上传，写入分段时为所属的分段上传，否则与 fileID 相同
+	part     int    // 分段编号，非零时写入分段上传的一个分段
+	opts     putOptions
+	keep     int   // 保留原文件的分片数
+	keptSize int64 // 保留的分片的总大小
+	tail     int   // 写入的分片之后沿用原文件的分片数，只在更新文件中间的分片时非零
+	tailSize int64
+	buf      []byte
+	chunks   []metadata.FileChunk
+	size     int64
+	err      error // 第一次上传失败的错误，之后的写入都返回该错误
+	closed   bool
+	entry    *Entry // 提交后的文件
+	result   *Part  // 提交后的分段
+	unlock   func() // 结束写入期间持有的排他租约
+}
+
+// 创建新文件用于写入，path 已存在时返回 fs.ErrExist，指定 WithReplace 时提交后替换原文件
+func (c *Client) Create(ctx context.Context, path string, opts ...PutOption) (*Writer, error) {
+	path = metadata.CleanPath(path)
+	options, err := c.createOptions(ctx, "create", path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.newWriter(ctx, path, options, nil, 0)
+}
+
+// 检查可以在 path 创建文件并合并上传选项：父目录须已存在，path 已存在时须指定 WithReplace
+func (c *Client) createOptions(ctx context.Context, op, path string, opts []PutOption) (putOptions, error) {
+	var options putOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if entry, err := c.Stat(ctx, path); err == nil {
+		if entry.IsDirectory {
+			return options, &fs.PathError{Op: op, Path: path, Err: ErrIsDir}
+		}
+		if !options.replace {
+			return options, &fs.PathError{Op: op, Path: path, Err: fs.ErrExist}
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return options, err
+	}
+	dir, _ := metadata.SplitPath(path)
+	parent, err := c.Stat(ctx, dir)
+	if err != nil {
+		return options, err
+	}
+	if !parent.IsDirectory {
+		return options, &fs.PathError{Op: op, Path: dir, Err: ErrNotDir}
+	}
+	return c.putOptions(ctx, dir, opts)
+}
+
+// 取得排他租约、登记上传并返回 Writer，base 非 nil 时更新该文件并保留其前 keep 个分片
+// 租约持有到 Writer 关闭；更新时 base 在取得租约前读取，其间文件被修改则提交时返回 ErrConflict
+func (c *Client) newWriter(ctx context.Context, path string, opts putOptions, base *Entry, keep int) (*Writer, error) {
+	unlock, err := c.lockForWrite(ctx, "put", path)
+	if err != nil {
+		return nil, err
+	}
+	fileID := newFileID(path)
+	if err := c.beginUpload(ctx, path, fileID, opts, base, keep); err != nil {
+		unlock()
+		return nil, err
+	}
+	w := &Writer{c: c, ctx: ctx, path: path, fileID: fileID, uploadID: fileID, opts: opts, keep: keep, unlock: unlock}
+	if base != nil {
+		for _, chunk := range base.Chunks[:keep] {
+			w.keptSize += chunk.Size
+		}
+	}
+	return w, nil
+}
+
+// 写入 p，缓冲满一个分片即上传；上传失败时返回 p 中已随分片上传的字节数，之后的写入都返回同一错误
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	buffered := len(w.buf) // 之前写入、尚未上传的字节
+	w.buf = append(w.buf, p...)
+	flushed := 0
+	for int64(len(w.buf)) >= w.opts.chunkSize {
+		if err := w.flush(w.buf[:w.opts.chunkSize]); err != nil {
+			w.err = err
+			// 只有已上传的分片中来自 p 的字节算作已写入
+			return max(flushed-buffered, 0), err
+		}
+		flushed += int(w.opts.chunkSize)
+		w.buf = append([]byte(nil), w.buf[w.opts.chunkSize:]...) // 不保留已上传分片的内存
+	}
+	return len(p), nil
+}
+
+// 将一个分片上传到存储节点，全零的分片记为空洞，不写入存储节点
+func (w *Writer) flush(data []byte) error {
+	chunk := w.nextChunk(int64(len(data)))
+	if isZero(data) {
+		return w.record(chunk)
+	}
+	chunk.ChunkID = fmt.Sprintf("%s_%d", w.fileID, chunk.ChunkNumber) // 唯一分片标识符
+	locations, err := w.c.cluster.place(chunk.ChunkNumber, chunk.ChunkID, w.opts.replicas)
+	if err != nil {
+		return err
+	}
+
+	chunk.Checksum = metadata.Checksum(data)
+	acked, pending, err := w.c.cluster.writeReplicas(w.ctx, locations, chunk.ChunkID, data, chunk.Checksum, w.opts.writeQuorum)
+	if err != nil {
+		return &ChunkError{Op: "upload", ChunkNumber: chunk.ChunkNumber, Nodes: locations, Err: err}
+	}
+	chunk.StorageLocation = acked[0] // 主副本
+	chunk.Replicas = acked[1:]       // 其余副本
+	chunk.Pending = pending          // 等待后台修复的副本
+	if err := w.record(chunk); err != nil {
+		return err
+	}
+	w.c.logf("Uploaded chunk %d to nodes %v successfully.\n", chunk.ChunkNumber, acked)
+	return nil
+}
+
+// data 是否全为零字节
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 下一个分片，尚未分配标识符和存储位置
+func (w *Writer) nextChunk(size int64) metadata.FileChunk {
+	_, filename := metadata.SplitPath(w.path)
+	return metadata.FileChunk{
+		FileID:       w.fileID,
+		ChunkNumber:  w.keep + len(w.chunks),
+		OriginalName: filename,
+		Size:         size,
+	}
+}
+
+// 在元数据服务登记已写入的分片（或空洞）
+func (w *Writer) record(chunk metadata.FileChunk) error {
+	var err error
+	if w.part != 0 {
+		err = w.c.addPartChunk(w.ctx, w.path, w.uploadID, w.fileID, w.part, chunk)
+	} else {
+		err = w.c.addChunk(w.ctx, w.path, w.uploadID, chunk)
+	}
+	if err != nil {
+		return err
+	}
+	w.chunks = append(w.chunks, chunk)
+	w.size += chunk.Size
+	return nil
+}
+
+// 写入 n 个零字节：先补满缓冲中的分片，其余的零直接记为一个空洞，不分配内存
+func (w *Writer) writeZeros(n int64) error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.buf) > 0 {
+		fill := min(n, w.opts.chunkSize-int64(len(w.buf)))
+		if _, err := w.Write(make([]byte, fill)); err != nil {
+			return err
+		}
+		n -= fill
+	}
+	if n > 0 {
+		if err := w.record(w.nextChunk(n)); err != nil {
+			w.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// 上传剩余数据并提交元数据，之后释放写入期间持有的排他租约
+// 写入失败后 Close 放弃上传，由元数据服务回收已上传的分片
+func (w *Writer) Close() error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+	defer w.unlock()
+	if w.err == nil && len(w.buf) > 0 {
+		w.err = w.flush(w.buf)
+		w.buf = nil
+	}
+	if w.err != nil {
+		// 分段写入失败时保留分段上传，已写入的分片在完成或放弃分段上传时回收
+		if w.part == 0 {
+			w.c.abortUpload(w.ctx, w.path, w.uploadID)
+		}
+		return w.err
+	}
+	if w.part != 0 {
+		part, err := w.c.commitPart(w.ctx, w.path, w.uploadID, w.fileID, w.part, w.size, len(w.chunks))
+		w.result = part
+		return err
+	}
+	entry, err := w.c.commitUpload(w.ctx, w.path, w.uploadID, w.keptSize+w.size+w.tailSize, w.keep+len(w.chunks)+w.tail, len(w.chunks))
+	if err != nil {
+		return err
+	}
+	w.entry = entry
+	return nil
+}
+
+// 放弃写入，已上传的分片由元数据服务回收，原文件保持不变
+func (w *Writer) abort(err error) error {
+	if w.err == nil {
+		w.err = err
+	}
+	w.Close()
+	return err
+}
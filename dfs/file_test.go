@@ -0,0 +1,51 @@
+package dfs
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 分片上传失败时，Write 返回 p 中已随之前的分片上传的字节数
+func TestWriterShortWrite(t *testing.T) {
+	const chunkSize = 8
+	tests := []struct {
+		name     string
+		buffered int // 之前写入、尚未上传的字节数
+		write    int
+		fail     string // 上传失败的分片
+		want     int
+		wantErr  bool
+	}{
+		{name: "all chunks uploaded", write: 20, fail: "_5", want: 20},
+		{name: "first chunk fails", write: 20, fail: "_0", want: 0, wantErr: true},
+		{name: "third chunk fails", write: 30, fail: "_2", want: 16, wantErr: true},
+		{name: "buffered bytes not counted", buffered: 4, write: 30, fail: "_2", want: 12, wantErr: true},
+		{name: "fails in buffered chunk", buffered: 4, write: 10, fail: "_0", want: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newFakeCluster(t)
+			w, err := fc.Create(context.Background(), "/f", WithChunkSize(chunkSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, err := w.Write(bytes.Repeat([]byte("b"), tt.buffered)); n != tt.buffered || err != nil {
+				t.Fatalf("buffered write = %d, %v", n, err)
+			}
+			fc.node.setFail(tt.fail, status.Error(codes.Internal, "disk full"))
+			n, err := w.Write(bytes.Repeat([]byte("w"), tt.write))
+			if n != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("Write = %d, %v; want %d, error %v", n, err, tt.want, tt.wantErr)
+			}
+			if tt.wantErr {
+				if n, again := w.Write([]byte("x")); n != 0 || again == nil {
+					t.Errorf("Write after failure = %d, %v; want the earlier error", n, again)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package dfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+)
+
+var (
+	_ fs.FS         = (*FS)(nil)
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+)
+
+// 以 io/fs 接口访问的目录树，名称为不带前导斜杠的相对路径，"." 表示根目录
+// 例如 FS 中的 "docs/a.txt" 对应 /docs/a.txt
+type FS struct {
+	c   *Client
+	ctx context.Context
+}
+
+// 返回以 io/fs 接口访问的目录树，所有操作使用 ctx
+func (c *Client) FS(ctx context.Context) *FS {
+	return &FS{c: c, ctx: ctx}
+}
+
+// 将 io/fs 名称转换为目录树中的绝对路径
+func (fsys *FS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return metadata.CleanPath("/" + name), nil
+}
+
+// 将客户端错误中的绝对路径换回 io/fs 名称
+func fsError(op, name string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &fs.PathError{Op: op, Path: name, Err: pathErr.Err}
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (fsys *FS) Open(name string) (fs.File, error) {
+	p, err := fsys.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := fsys.c.Stat(fsys.ctx, p)
+	if err != nil {
+		return nil, fsError("open", name, err)
+	}
+	if entry.IsDirectory {
+		return &fsDir{fsys: fsys, name: name, entry: entry}, nil
+	}
+	f, err := fsys.c.Open(fsys.ctx, p)
+	if err != nil {
+		return nil, fsError("open", name, err)
+	}
+	return &fsFile{File: f, name: name}, nil
+}
+
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	p, err := fsys.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := fsys.c.Stat(fsys.ctx, p)
+	if err != nil {
+		return nil, fsError("stat", name, err)
+	}
+	return fileInfo{name: path.Base(name), entry: entry}, nil
+}
+
+// 按名称排序返回目录下的所有条目
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := fsys.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := fsys.c.List(fsys.ctx, p)
+	if err != nil {
+		return nil, fsError("readdir", name, err)
+	}
+	dirEntries := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		dirEntries = append(dirEntries, fs.FileInfoToDirEntry(fileInfo{name: entry.Name, entry: entry}))
+	}
+	sort.Slice(dirEntries, func(i, j int) bool { return dirEntries[i].Name() < dirEntries[j].Name() })
+	return dirEntries, nil
+}
+
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	file, ok := f.(*fsFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: ErrIsDir}
+	}
+	data := make([]byte, file.File.Stat().Size)
+	if _, err := io.ReadFull(file, data); err != nil {
+		return nil, fsError("read", name, err)
+	}
+	return data, nil
+}
+
+// 文件或目录的 fs.FileInfo，Sys 返回 *metadata.FileMetadata
+type fileInfo struct {
+	name  string
+	entry *Entry
+}
+
+func (fi fileInfo) Name() string { return fi.name }
+func (fi fileInfo) Size() int64  { return fi.entry.Size }
+func (fi fileInfo) IsDir() bool  { return fi.entry.IsDirectory }
+func (fi fileInfo) Sys() any     { return fi.entry.FileMetadata }
+
+// 目录树不记录权限，文件只读，目录可读可进入
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.entry.IsDirectory {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// 目录没有修改时间，使用创建时间
+func (fi fileInfo) ModTime() time.Time {
+	if fi.entry.ModificationTime.IsZero() {
+		return fi.entry.CreationTime
+	}
+	return fi.entry.ModificationTime
+}
+
+// 通过 FS 打开的文件
+type fsFile struct {
+	*File
+	name string
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return fileInfo{name: path.Base(f.name), entry: f.File.Stat()}, nil
+}
+
+// 通过 FS 打开的目录，首次 ReadDir 时才列出条目
+type fsDir struct {
+	fsys    *FS
+	name    string
+	entry   *Entry
+	entries []fs.DirEntry
+	listed  bool
+	closed  bool
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return fileInfo{name: path.Base(d.name), entry: d.entry}, nil
+}
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: ErrIsDir}
+}
+
+func (d *fsDir) Close() error {
+	if d.closed {
+		return fs.ErrClosed
+	}
+	d.closed = true
+	return nil
+}
+
+// 依次返回目录条目，n > 0 时每次最多返回 n 个，读完后返回 io.EOF
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: fs.ErrClosed}
+	}
+	if !d.listed {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.listed = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
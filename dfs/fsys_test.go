@@ -0,0 +1,232 @@
+package dfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"net"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 进程内的元数据服务，目录树由测试直接填充；上传和租约的部分见 update_test.go
+type fakeMeta struct {
+	metapb.UnimplementedMetadataServer
+	mu      sync.Mutex
+	tree    *metadata.FileTree
+	uploads map[string]*fakeUpload
+}
+
+func (m *fakeMeta) Stat(ctx context.Context, req *metapb.PathRequest) (*metapb.FileMetadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.tree.Lookup(req.Path)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return node.Metadata.ToProto(), nil
+}
+
+func (m *fakeMeta) List(ctx context.Context, req *metapb.PathRequest) (*metapb.ListResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries, err := m.tree.ListAt(req.Path)
+	switch {
+	case err == metadata.ErrNotDirectory:
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	resp := &metapb.ListResponse{}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, entry.ToProto())
+	}
+	return resp, nil
+}
+
+// 进程内的存储节点，分片由测试直接写入或经流水线写入
+type fakeNode struct {
+	pb.UnimplementedFileSystemServer
+	id       string
+	addr     string
+	peers    map[string]*fakeNode // 集群中按地址索引的所有节点，用于模拟流水线转发
+	mu       sync.Mutex
+	chunks   map[string][]byte
+	modTimes map[string]time.Time // 分片的修改时间，未记录的报告为未知
+	fail     error                // 非空时写入 ID 以 failOnly 结尾的分片返回该错误
+	failOnly string
+	writes   int // 成功写入的分片数
+
+	readDelay time.Duration // 读取分片前的等待时间
+	reads     int           // 收到的读取请求数
+	canceled  chan string   // 读取在等待期间被取消时发送分片 ID
+}
+
+func (n *fakeNode) NodeInfo(ctx context.Context, req *pb.NodeInfoRequest) (*pb.NodeInfoResponse, error) {
+	return &pb.NodeInfoResponse{Id: n.id}, nil
+}
+
+func (n *fakeNode) ReadFile(ctx context.Context, req *pb.ReadRequest) (*pb.ReadResponse, error) {
+	n.mu.Lock()
+	n.reads++
+	delay, canceled := n.readDelay, n.canceled
+	n.mu.Unlock()
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			if canceled != nil {
+				canceled <- req.Filename
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	data, exists := n.chunks[req.Filename]
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "chunk %s not found", req.Filename)
+	}
+	if req.Length > 0 && req.Length < int64(len(data)) {
+		data = data[:req.Length]
+	}
+	return &pb.ReadResponse{Data: data}, nil
+}
+
+// 进程内的元数据服务和存储节点，以及连接它们的客户端
+type fakeCluster struct {
+	*Client
+	meta  *fakeMeta
+	node  *fakeNode // nodes[0]
+	nodes []*fakeNode
+}
+
+// 只有一个存储节点、每个分片一个副本的集群
+func newFakeCluster(t *testing.T) *fakeCluster {
+	t.Helper()
+	return newFakeClusterOf(t, 1)
+}
+
+// n 个存储节点 node1..noden 的集群，默认每个分片 n 个副本
+func newFakeClusterOf(t *testing.T, n int) *fakeCluster {
+	t.Helper()
+	meta := &fakeMeta{tree: metadata.NewFileTree(), uploads: make(map[string]*fakeUpload)}
+	metaAddr := serve(t, func(s *grpc.Server) { metapb.RegisterMetadataServer(s, meta) })
+
+	var nodes []*fakeNode
+	var configs []NodeConfig
+	peers := make(map[string]*fakeNode)
+	for i := 1; i <= n; i++ {
+		node := &fakeNode{id: fmt.Sprintf("node%d", i), peers: peers, chunks: make(map[string][]byte), modTimes: make(map[string]time.Time)}
+		node.addr = serve(t, func(s *grpc.Server) { pb.RegisterFileSystemServer(s, node) })
+		peers[node.addr] = node
+		nodes = append(nodes, node)
+		configs = append(configs, NodeConfig{ID: node.id, Addr: node.addr})
+	}
+
+	c, err := New(Config{
+		MetaAddr: metaAddr,
+		Nodes:    configs,
+		Replicas: n,
+		Owner:    "test",
+		Retry:    RetryPolicy{InitialBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return &fakeCluster{Client: c, meta: meta, node: nodes[0], nodes: nodes}
+}
+
+// 在本地端口上启动 gRPC 服务，测试结束时停止
+func serve(t *testing.T, register func(*grpc.Server)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	register(s)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func (fc *fakeCluster) mkdir(t *testing.T, path string) {
+	t.Helper()
+	if err := fc.meta.tree.MkdirAt(path); err != nil {
+		t.Fatalf("mkdir %s: %v", path, err)
+	}
+}
+
+// 按 chunkSize 切分 data 写入存储节点并登记文件，全零的分片登记为空洞
+func (fc *fakeCluster) addFile(t *testing.T, path string, data []byte, chunkSize int64) {
+	t.Helper()
+	var pieces [][]byte
+	for off := int64(0); off < int64(len(data)); off += chunkSize {
+		pieces = append(pieces, data[off:min(off+chunkSize, int64(len(data)))])
+	}
+	fc.addChunks(t, path, chunkSize, pieces...)
+}
+
+// 以 pieces 为分片登记文件，全零的分片登记为空洞，大小可以超过 chunkSize
+func (fc *fakeCluster) addChunks(t *testing.T, path string, chunkSize int64, pieces ...[]byte) {
+	t.Helper()
+	dir, name := metadata.SplitPath(path)
+	now := time.Now()
+	meta := &metadata.FileMetadata{Name: name, CreationTime: now, ModificationTime: now, ChunkSize: chunkSize, Replicas: 1}
+	for number, part := range pieces {
+		chunk := metadata.FileChunk{ChunkNumber: number, OriginalName: name, Size: int64(len(part))}
+		if !isZero(part) {
+			chunk.ChunkID = fmt.Sprintf("%s_%d", path, number)
+			chunk.FileID = path
+			chunk.Checksum = metadata.Checksum(part)
+			chunk.StorageLocation = fc.node.id
+			fc.node.chunks[chunk.ChunkID] = part
+		}
+		meta.Chunks = append(meta.Chunks, chunk)
+		meta.Size += chunk.Size
+	}
+	if err := fc.meta.tree.AddFileAt(dir, meta); err != nil {
+		t.Fatalf("add %s: %v", path, err)
+	}
+}
+
+func TestFS(t *testing.T) {
+	fc := newFakeCluster(t)
+	fc.mkdir(t, "/docs")
+	fc.mkdir(t, "/docs/nested")
+	fc.mkdir(t, "/docs/nested/deep")
+	fc.mkdir(t, "/emptydir")
+	fc.addFile(t, "/docs/a.txt", []byte("hello, world\n"), 64)
+	fc.addFile(t, "/empty.txt", nil, 64)
+	// 多个分片，中间一个为空洞，最后一个不满
+	multi := append(bytes.Repeat([]byte("01234567"), 4), make([]byte, 8)...)
+	multi = append(multi, "tail"...)
+	fc.addFile(t, "/docs/nested/deep/multi.bin", multi, 8)
+
+	fsys := fc.FS(context.Background())
+	if err := fstest.TestFS(fsys, "docs/a.txt", "empty.txt", "emptydir", "docs/nested/deep/multi.bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(fsys, "docs/nested/deep/multi.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, multi) {
+		t.Fatalf("multi.bin = %q, want %q", data, multi)
+	}
+}
@@ -0,0 +1,102 @@
+package dfs
+
+import (
+	"context"
+	"time"
+
+	pb "grpc-distributed-fs/proto/fs"
+	metapb "grpc-distributed-fs/proto/meta"
+)
+
+// 垃圾回收选项
+type GCOptions struct {
+	DryRun bool          // 只报告孤儿分片，不删除
+	Grace  time.Duration // 孤儿分片的最小年龄，较新的分片可能属于正在进行的上传
+}
+
+// 一个孤儿分片
+type OrphanChunk struct {
+	ChunkID string
+	Node    string
+	Size    int64
+	ModTime time.Time // 节点未记录修改时间时为零值
+	Deleted bool
+	Err     error // 删除失败的原因
+}
+
+// 垃圾回收结果
+type GCReport struct {
+	Orphans     []OrphanChunk
+	OrphanBytes int64
+	Deleted     int
+	NodeErrors  map[string]error // 无法列出分片的节点
+}
+
+// 回收元数据中不再引用的分片（失败的上传、删除留下的孤儿分片）
+// 只回收早于宽限期的分片，避免误删正在上传的分片
+func (c *Client) CollectGarbage(ctx context.Context, opts GCOptions) (*GCReport, error) {
+	// 元数据中引用的所有分片，包括未提交上传的分片（由元数据服务在上传过期时回收）
+	// 和追加租约的分片（在租约有效期内随时可能提交）；必须在列出节点的分片之前取得
+	referenced, err := c.referencedChunks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &GCReport{NodeErrors: make(map[string]error)}
+	cutoff := time.Now().Add(-opts.Grace)
+	for _, node := range c.cluster.sortedNodes() {
+		var resp *pb.ListResponse
+		err := c.cluster.call(ctx, node.ID, c.cluster.retry.rpcTimeout, func(ctx context.Context, node *storageNode) (err error) {
+			resp, err = node.ListFiles(ctx, &pb.ListRequest{})
+			return err
+		})
+		if err != nil {
+			report.NodeErrors[node.ID] = err
+			continue
+		}
+
+		for _, entry := range resp.Entries {
+			if referenced[entry.Name] {
+				continue
+			}
+			orphan := OrphanChunk{ChunkID: entry.Name, Node: node.ID, Size: entry.Size}
+			if entry.ModTime != 0 {
+				orphan.ModTime = time.Unix(entry.ModTime, 0)
+				if orphan.ModTime.After(cutoff) {
+					continue
+				}
+			}
+			report.OrphanBytes += entry.Size
+
+			if !opts.DryRun {
+				orphan.Err = c.cluster.call(ctx, node.ID, c.cluster.retry.rpcTimeout, func(ctx context.Context, node *storageNode) error {
+					_, err := node.DeleteFile(ctx, &pb.DeleteRequest{Filename: entry.Name})
+					return err
+				})
+				if orphan.Err == nil {
+					orphan.Deleted = true
+					report.Deleted++
+				}
+			}
+			report.Orphans = append(report.Orphans, orphan)
+		}
+	}
+	return report, nil
+}
+
+// 元数据服务在同一时刻取得的全部被引用分片
+func (c *Client) referencedChunks(ctx context.Context) (map[string]bool, error) {
+	var resp *metapb.ReferencedChunksResponse
+	err := c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+		resp, err = c.meta.ReferencedChunks(ctx, &metapb.Empty{})
+		return err
+	})
+	if err != nil {
+		return nil, pathError("gc", "/", err)
+	}
+	referenced := make(map[string]bool, len(resp.ChunkIds))
+	for _, chunkID := range resp.ChunkIds {
+		referenced[chunkID] = true
+	}
+	return referenced, nil
+}
@@ -0,0 +1,125 @@
+package dfs
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+	metapb "grpc-distributed-fs/proto/meta"
+)
+
+func (m *fakeMeta) ReferencedChunks(ctx context.Context, req *metapb.Empty) (*metapb.ReferencedChunksResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	resp := &metapb.ReferencedChunksResponse{}
+	m.tree.Walk(func(path string, meta *metadata.FileMetadata) {
+		for _, chunk := range meta.Chunks {
+			resp.ChunkIds = append(resp.ChunkIds, chunk.ChunkID)
+		}
+	})
+	for _, u := range m.uploads {
+		for _, chunk := range u.chunks {
+			resp.ChunkIds = append(resp.ChunkIds, chunk.ChunkID)
+		}
+	}
+	return resp, nil
+}
+
+func (n *fakeNode) ListFiles(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	resp := &pb.ListResponse{}
+	for name, data := range n.chunks {
+		info := &pb.FileInfo{Name: name, Size: int64(len(data))}
+		if modTime, exists := n.modTimes[name]; exists {
+			info.ModTime = modTime.Unix()
+		}
+		resp.Entries = append(resp.Entries, info)
+	}
+	return resp, nil
+}
+
+func (n *fakeNode) DeleteFile(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.chunks, req.Filename)
+	return &pb.DeleteResponse{}, nil
+}
+
+func TestCollectGarbage(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name    string
+		opts    GCOptions
+		orphans []string // 报告的孤儿分片
+		kept    []string // 回收后节点上的分片
+	}{
+		{
+			name:    "orphans deleted",
+			orphans: []string{"old", "recent", "unknown"},
+			kept:    []string{"/f_0", "/f_1", "pending"},
+		},
+		{
+			name:    "dry run",
+			opts:    GCOptions{DryRun: true},
+			orphans: []string{"old", "recent", "unknown"},
+			kept:    []string{"/f_0", "/f_1", "old", "pending", "recent", "unknown"},
+		},
+		{
+			name:    "grace period",
+			opts:    GCOptions{Grace: 10 * time.Minute},
+			orphans: []string{"old", "unknown"},
+			kept:    []string{"/f_0", "/f_1", "pending", "recent"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newFakeCluster(t)
+			fc.addFile(t, "/f", []byte("0123456789"), 8)
+			// 未提交上传的分片只在上传中登记，不属于任何文件
+			fc.meta.uploads["u"] = &fakeUpload{
+				req:    &metapb.BeginUploadRequest{Path: "/g", UploadId: "u"},
+				chunks: map[int]metadata.FileChunk{0: {ChunkID: "pending", StorageLocation: "node1"}},
+			}
+			fc.node.chunks["pending"] = []byte("p")
+			fc.node.modTimes["pending"] = old
+			fc.node.chunks["old"] = []byte("old")
+			fc.node.modTimes["old"] = old
+			fc.node.chunks["recent"] = []byte("recent")
+			fc.node.modTimes["recent"] = time.Now()
+			fc.node.chunks["unknown"] = []byte("unknown") // 节点未记录修改时间
+
+			report, err := fc.CollectGarbage(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var orphans []string
+			var bytes int64
+			for _, orphan := range report.Orphans {
+				orphans = append(orphans, orphan.ChunkID)
+				bytes += orphan.Size
+				if orphan.Deleted == tt.opts.DryRun || orphan.Err != nil {
+					t.Errorf("orphan %s: deleted %v, error %v", orphan.ChunkID, orphan.Deleted, orphan.Err)
+				}
+			}
+			slices.Sort(orphans)
+			if !slices.Equal(orphans, tt.orphans) {
+				t.Errorf("orphans = %v, want %v", orphans, tt.orphans)
+			}
+			if report.OrphanBytes != bytes {
+				t.Errorf("orphan bytes = %d, want %d", report.OrphanBytes, bytes)
+			}
+			var kept []string
+			for name := range fc.node.chunks {
+				kept = append(kept, name)
+			}
+			slices.Sort(kept)
+			if !slices.Equal(kept, tt.kept) {
+				t.Errorf("chunks left = %v, want %v", kept, tt.kept)
+			}
+		})
+	}
+}
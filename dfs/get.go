@@ -0,0 +1,225 @@
+package dfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+)
+
+// 下载选项
+type GetOption func(*getOptions)
+
+type getOptions struct {
+	overwrite bool
+}
+
+// 本地文件已存在时覆盖，不指定时返回 fs.ErrExist
+func WithOverwrite() GetOption {
+	return func(o *getOptions) {
+		o.overwrite = true
+	}
+}
+
+// 下载 src 并保存为本地文件 localPath，localPath 为已存在的目录时保存到该目录下
+// 先写入同目录下的中间文件，全部分片写完后再重命名，避免留下不完整的文件
+// 下载中断时保留中间文件，再次下载时跳过其中已完整的分片
+func (c *Client) Get(ctx context.Context, src, localPath string, opts ...GetOption) (*Entry, error) {
+	var options getOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	entry, err := c.Stat(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDirectory {
+		return nil, &fs.PathError{Op: "get", Path: entry.Path, Err: ErrIsDir}
+	}
+	if info, err := os.Stat(localPath); err == nil && info.IsDir() {
+		localPath = filepath.Join(localPath, entry.Name)
+	}
+	// 下载前检查一次，避免下载完才发现不能覆盖
+	if err := checkClobber(localPath, options.overwrite); err != nil {
+		return nil, err
+	}
+
+	partial, err := os.OpenFile(partialPath(localPath), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer partial.Close()
+
+	// 并发下载分片，每个分片直接写到其在文件中的偏移处
+	var tasks []transferTask
+	var offset int64
+	resumed := 0
+	for _, chunk := range entry.Chunks {
+		chunkOffset := offset
+		offset += chunk.Size
+		if chunk.IsHole() {
+			// 空洞不需要下载，只清除续传文件中该范围的旧数据，超出部分由最后的 Truncate 补零
+			if err := zeroRange(partial, chunkOffset, chunk.Size); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if chunkPresent(partial, chunkOffset, chunk) {
+			resumed++
+			continue
+		}
+		tasks = append(tasks, transferTask{nodes: c.readNodes(chunk), run: func() error {
+			data, err := c.fetchChunk(ctx, chunk)
+			if err != nil {
+				return err
+			}
+			if _, err := partial.WriteAt(data, chunkOffset); err != nil {
+				return err
+			}
+
+			c.logf("Downloaded chunk %d successfully.\n", chunk.ChunkNumber)
+			return nil
+		}})
+	}
+	if resumed > 0 {
+		c.logf("Resuming download: %d of %d chunk(s) already downloaded.\n", resumed, len(entry.Chunks))
+	}
+	if err := c.cluster.runTransfers(tasks); err != nil {
+		return nil, err
+	}
+
+	// 将拼接后的数据保存到本地
+	err = partial.Truncate(offset)
+	if err == nil {
+		err = partial.Close()
+	}
+	if err == nil {
+		err = checkClobber(localPath, options.overwrite)
+	}
+	if err == nil {
+		err = os.Rename(partialPath(localPath), localPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// 不覆盖时本地文件必须不存在，覆盖时也不能替换目录
+func checkClobber(localPath string, overwrite bool) error {
+	info, err := os.Lstat(localPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	case info.IsDir():
+		return &fs.PathError{Op: "get", Path: localPath, Err: ErrIsDir}
+	case !overwrite:
+		return &fs.PathError{Op: "get", Path: localPath, Err: fs.ErrExist}
+	}
+	return nil
+}
+
+// 读取分片可能访问的节点：主副本，对冲读取时还有其余副本
+func (c *Client) readNodes(chunk metadata.FileChunk) []string {
+	nodes := chunk.Locations()
+	if c.hedgeDelay <= 0 && len(nodes) > 1 {
+		nodes = nodes[:1]
+	}
+	return nodes
+}
+
+// 读取分片的一次尝试的结果
+type readResult struct {
+	node string
+	data []byte
+	err  error
+}
+
+// 读取分片，所有副本都因可重试的错误失败时，按重试策略退避后再轮询一遍副本
+func (c *Client) fetchChunk(ctx context.Context, chunk metadata.FileChunk) (data []byte, err error) {
+	if chunk.IsHole() {
+		return make([]byte, chunk.Size), nil
+	}
+	err = c.cluster.retry.do(ctx, 0, true, func(ctx context.Context) (err error) {
+		data, err = c.fetchReplicas(ctx, chunk)
+		return err
+	})
+	return data, err
+}
+
+// 按副本记录顺序（主副本在前）逐个尝试，直到某个副本返回完整且校验一致的数据
+// 某次尝试超过 HedgeDelay 仍未返回时，同时向下一个副本发出请求，采用先返回的结果
+// 每次尝试使用各自的超时时间，不受之前失败尝试的影响
+func (c *Client) fetchReplicas(ctx context.Context, chunk metadata.FileChunk) ([]byte, error) {
+	nodes := chunk.Locations()
+	if len(nodes) == 0 {
+		return nil, &ChunkError{Op: "download", ChunkNumber: chunk.ChunkNumber, Err: errors.New("no replicas recorded")}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // 取得结果后取消其余仍在进行的请求
+
+	results := make(chan readResult, len(nodes))
+	next, inflight := 0, 0
+	var hedge <-chan time.Time
+	launch := func() {
+		node := nodes[next]
+		next++
+		inflight++
+		go func() {
+			data, err := c.readReplica(ctx, node, chunk)
+			results <- readResult{node: node, data: data, err: err}
+		}()
+		hedge = nil
+		if c.hedgeDelay > 0 && next < len(nodes) {
+			hedge = time.After(c.hedgeDelay)
+		}
+	}
+
+	launch()
+	var errs []error
+	for inflight > 0 {
+		select {
+		case result := <-results:
+			inflight--
+			if result.err == nil {
+				return result.data, nil
+			}
+			errs = append(errs, fmt.Errorf("node %s: %w", result.node, result.err))
+			if next < len(nodes) {
+				c.logf("Failed to download chunk %d from node %s, trying node %s: %v\n", chunk.ChunkNumber, result.node, nodes[next], result.err)
+				launch()
+			}
+		case <-hedge:
+			c.logf("Chunk %d is slow, sending hedged request to node %s.\n", chunk.ChunkNumber, nodes[next])
+			launch()
+		}
+	}
+	return nil, &ChunkError{Op: "download", ChunkNumber: chunk.ChunkNumber, Nodes: nodes, Err: errors.Join(errs...)}
+}
+
+// 从一个副本读取分片并校验大小和校验值，只尝试一次，失败时由调用方换下一个副本
+func (c *Client) readReplica(ctx context.Context, nodeID string, chunk metadata.FileChunk) ([]byte, error) {
+	var resp *pb.ReadResponse
+	err := attemptOnce(ctx, c.cluster.retry.chunkTimeout(chunk.Size), c.cluster.guard(nodeID, func(ctx context.Context, node *storageNode) (err error) {
+		resp, err = node.ReadFile(ctx, &pb.ReadRequest{Filename: chunk.ChunkID, Length: chunk.Size})
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(resp.Data)) != chunk.Size {
+		return nil, fmt.Errorf("got %d bytes, want %d", len(resp.Data), chunk.Size)
+	}
+	if chunk.Checksum != "" && metadata.Checksum(resp.Data) != chunk.Checksum {
+		return nil, errors.New("checksum mismatch")
+	}
+	return resp.Data, nil
+}
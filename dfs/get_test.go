@@ -0,0 +1,71 @@
+package dfs
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+)
+
+// 主副本慢时向下一个副本发出对冲请求，采用先返回的结果并取消另一个请求
+func TestHedgedRead(t *testing.T) {
+	const hedgeDelay = 100 * time.Millisecond
+	tests := []struct {
+		name       string
+		hedgeDelay time.Duration
+		delays     [2]time.Duration // node1、node2 读取分片前的等待时间
+		wantReads  [2]int
+		canceled   string // 被取消的请求所在节点
+		minElapsed time.Duration
+	}{
+		{name: "fast primary", hedgeDelay: hedgeDelay, wantReads: [2]int{1, 0}},
+		{name: "slow primary", hedgeDelay: hedgeDelay, delays: [2]time.Duration{10 * time.Second, 0}, wantReads: [2]int{1, 1}, canceled: "node1", minElapsed: hedgeDelay},
+		{name: "primary wins after hedge", hedgeDelay: hedgeDelay, delays: [2]time.Duration{3 * hedgeDelay, 10 * time.Second}, wantReads: [2]int{1, 1}, canceled: "node2", minElapsed: 3 * hedgeDelay},
+		{name: "hedging disabled", hedgeDelay: -1, delays: [2]time.Duration{3 * hedgeDelay, 0}, wantReads: [2]int{1, 0}, minElapsed: 3 * hedgeDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newFakeClusterOf(t, 2)
+			fc.hedgeDelay = tt.hedgeDelay
+			data := []byte("chunk data")
+			chunk := metadata.FileChunk{ChunkID: "c", Size: int64(len(data)), Checksum: metadata.Checksum(data), StorageLocation: "node1", Replicas: []string{"node2"}}
+			for i, node := range fc.nodes {
+				node.chunks["c"] = data
+				node.readDelay = tt.delays[i]
+				node.canceled = make(chan string, 1)
+			}
+
+			start := time.Now()
+			got, err := fc.fetchReplicas(context.Background(), chunk)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("got %q, want %q", got, data)
+			}
+			if elapsed < tt.minElapsed || elapsed > 5*time.Second {
+				t.Errorf("returned after %v, want at least %v", elapsed, tt.minElapsed)
+			}
+
+			for i, node := range fc.nodes {
+				node.mu.Lock()
+				reads := node.reads
+				node.mu.Unlock()
+				if reads != tt.wantReads[i] {
+					t.Errorf("%s read %d times, want %d", node.id, reads, tt.wantReads[i])
+				}
+				if node.id != tt.canceled {
+					continue
+				}
+				select {
+				case <-node.canceled:
+				case <-time.After(5 * time.Second):
+					t.Errorf("losing request to %s not canceled", node.id)
+				}
+			}
+		})
+	}
+}
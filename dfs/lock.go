@@ -0,0 +1,234 @@
+package dfs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
+)
+
+// 路径租约：由元数据服务授予，有效期内其他客户端不能修改该路径
+// 排他租约与其他任何租约冲突，共享租约只与排他租约冲突；租约只作用于路径本身，路径不必已存在
+type Lock struct {
+	Path    string
+	Holders []LockHolder // 路径上的全部有效租约
+}
+
+type LockHolder struct {
+	Owner     string
+	Exclusive bool
+	ExpiresAt time.Time
+}
+
+func lockFromProto(msg *metapb.LockInfo) *Lock {
+	lock := &Lock{Path: msg.Path}
+	for _, holder := range msg.Holders {
+		lock.Holders = append(lock.Holders, LockHolder{Owner: holder.Owner, Exclusive: holder.Exclusive, ExpiresAt: time.Unix(0, holder.ExpiresAt)})
+	}
+	return lock
+}
+
+// 本客户端在路径上持有的租约，持有期间在后台续期
+// 同一路径上的获取、续期和释放请求由 mu 串行，请求期间不持有 locksMu，不同路径的请求互不等待
+type heldLock struct {
+	refs int // 正在使用该条目的操作数，由 locksMu 保护，为 0 且未持有租约时从 locks 中删除
+
+	mu       sync.Mutex
+	held     bool               // 已从元数据服务取得租约
+	explicit bool               // 由 Lock 取得，Unlock 时释放
+	shared   bool               // 由 Lock 取得的是共享租约
+	writers  int                // 正在修改该路径的操作数，大于 0 时持有排他租约
+	stop     context.CancelFunc // 停止续期
+}
+
+func (h *heldLock) exclusive() bool {
+	return h.writers > 0 || (h.explicit && !h.shared)
+}
+
+// 默认的租约持有者名称：用户@主机:进程号
+func defaultOwner() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s:%d", name, host, os.Getpid())
+}
+
+// 在元数据服务的每个请求中附带租约持有者名称
+func ownerInterceptor(owner string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = grpcmd.AppendToOutgoingContext(ctx, metadata.LockOwnerKey, owner)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// 本客户端的租约持有者名称
+func (c *Client) Owner() string {
+	return c.owner
+}
+
+// 获取 path 上的排他或共享租约，持有期间在后台续期，直到 Unlock 或 Close
+// 已持有时转换为指定的模式；其他客户端持有冲突的租约时返回 *LockedError
+func (c *Client) Lock(ctx context.Context, path string, exclusive bool) (*Lock, error) {
+	path = metadata.CleanPath(path)
+	h := c.lockEntry(path)
+	defer c.unlockEntry(path, h)
+	info, err := c.lock(ctx, path, exclusive || h.writers > 0)
+	if err != nil {
+		return nil, pathError("lock", path, err)
+	}
+	if !h.held {
+		c.holdLock(path, h)
+	}
+	h.explicit, h.shared = true, !exclusive
+	return lockFromProto(info), nil
+}
+
+// 释放由 Lock 取得的租约，未持有时返回 ErrNotLocked
+func (c *Client) Unlock(ctx context.Context, path string) error {
+	path = metadata.CleanPath(path)
+	h := c.lockEntry(path)
+	defer c.unlockEntry(path, h)
+	if !h.held || !h.explicit {
+		return &fs.PathError{Op: "unlock", Path: path, Err: ErrNotLocked}
+	}
+	h.explicit = false
+	if h.writers > 0 {
+		return nil // 正在进行的修改结束时释放
+	}
+	return pathError("unlock", path, c.releaseLock(ctx, path, h))
+}
+
+// 在修改 path 期间持有排他租约，返回的函数结束持有
+// 已持有排他租约时直接使用，由 Lock 取得共享租约时临时升级为排他，结束后降级回共享
+func (c *Client) lockForWrite(ctx context.Context, op, path string) (func(), error) {
+	h := c.lockEntry(path)
+	defer c.unlockEntry(path, h)
+	if !h.held || !h.exclusive() {
+		if _, err := c.lock(ctx, path, true); err != nil {
+			return nil, pathError(op, path, err)
+		}
+		if !h.held {
+			c.holdLock(path, h)
+		}
+	}
+	h.writers++
+	return func() {
+		// 持有期间条目不会被删除，取得的仍是 h
+		h := c.lockEntry(path)
+		defer c.unlockEntry(path, h)
+		h.writers--
+		if h.writers > 0 {
+			return
+		}
+		// 调用方的 ctx 可能已取消，释放失败的租约在有效期后自动失效
+		ctx := context.WithoutCancel(ctx)
+		switch {
+		case !h.explicit:
+			c.releaseLock(ctx, path, h)
+		case h.shared:
+			c.lock(ctx, path, false)
+		}
+	}, nil
+}
+
+// 取得路径的租约条目并锁定，不存在时新建，用完后调用 unlockEntry
+func (c *Client) lockEntry(path string) *heldLock {
+	c.locksMu.Lock()
+	h := c.locks[path]
+	if h == nil {
+		h = &heldLock{}
+		c.locks[path] = h
+	}
+	h.refs++
+	c.locksMu.Unlock()
+	h.mu.Lock()
+	return h
+}
+
+// 解锁 lockEntry 取得的条目，没有其他操作使用且未持有租约时删除
+func (c *Client) unlockEntry(path string, h *heldLock) {
+	held := h.held
+	h.mu.Unlock()
+	c.locksMu.Lock()
+	defer c.locksMu.Unlock()
+	h.refs--
+	if h.refs == 0 && !held {
+		delete(c.locks, path)
+	}
+}
+
+// 记录新取得的租约并开始续期，调用方需持有 h.mu
+func (c *Client) holdLock(path string, h *heldLock) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.held, h.stop = true, cancel
+	go c.renewLock(ctx, path, h)
+}
+
+// 每隔三分之一有效期续期，续期失败时租约可能已被其他客户端取得，之后的修改返回 *LockedError
+func (c *Client) renewLock(ctx context.Context, path string, h *heldLock) {
+	ticker := time.NewTicker(c.lockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// 持有 h.mu 续期，避免续期请求晚于释放请求到达
+		h.mu.Lock()
+		if ctx.Err() == nil {
+			if _, err := c.lock(ctx, path, h.exclusive()); err != nil {
+				c.logf("Failed to renew lock on %s: %v\n", path, err)
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+// 停止续期并释放租约，调用方需持有 h.mu
+func (c *Client) releaseLock(ctx context.Context, path string, h *heldLock) error {
+	h.stop()
+	h.held = false
+	return c.callMeta(ctx, true, func(ctx context.Context) error {
+		_, err := c.meta.Unlock(ctx, &metapb.PathRequest{Path: path})
+		return err
+	})
+}
+
+// 释放本客户端持有的全部租约
+func (c *Client) releaseLocks() {
+	c.locksMu.Lock()
+	paths := make([]string, 0, len(c.locks))
+	for path := range c.locks {
+		paths = append(paths, path)
+	}
+	c.locksMu.Unlock()
+	for _, path := range paths {
+		h := c.lockEntry(path)
+		if h.held {
+			c.releaseLock(context.Background(), path, h)
+		}
+		c.unlockEntry(path, h)
+	}
+}
+
+// 向元数据服务获取或续期租约
+func (c *Client) lock(ctx context.Context, path string, exclusive bool) (*metapb.LockInfo, error) {
+	var info *metapb.LockInfo
+	err := c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+		info, err = c.meta.Lock(ctx, &metapb.LockRequest{Path: path, Exclusive: exclusive, Ttl: int64(c.lockTTL)})
+		return err
+	})
+	return info, err
+}
@@ -0,0 +1,174 @@
+package dfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+)
+
+// 分段上传：登记上传 ID 后，各分段独立写入并提交，可以来自不同客户端、顺序任意，最后按分段编号拼接为一个文件
+// 完成时只拼接各分段的分片列表，不复制数据；超过有效期没有进展的分段上传由元数据服务回收其分片
+type MultipartUpload struct {
+	c    *Client
+	ID   string
+	Path string
+	opts putOptions
+}
+
+// 已提交的分段
+type Part struct {
+	Number      int
+	ID          string // 分段写入的标识符，同一编号再次写入时替换之前的写入
+	Size        int64
+	Chunks      int
+	CommittedAt time.Time
+}
+
+func partFromProto(msg *metapb.PartInfo) Part {
+	return Part{
+		Number:      int(msg.PartNumber),
+		ID:          msg.PartId,
+		Size:        msg.Size,
+		Chunks:      int(msg.Chunks),
+		CommittedAt: time.Unix(0, msg.CommittedAt),
+	}
+}
+
+// 开始分段上传到 path，path 已存在时返回 fs.ErrExist，指定 WithReplace 时完成后替换原文件
+func (c *Client) CreateMultipartUpload(ctx context.Context, path string, opts ...PutOption) (*MultipartUpload, error) {
+	path = metadata.CleanPath(path)
+	options, err := c.createOptions(ctx, "multipart", path, opts)
+	if err != nil {
+		return nil, err
+	}
+	options.multipart = true
+	id := newFileID(path)
+	if err := c.beginUpload(ctx, path, id, options, nil, 0); err != nil {
+		return nil, err
+	}
+	return &MultipartUpload{c: c, ID: id, Path: path, opts: options}, nil
+}
+
+// 按上传 ID 打开其他客户端开始的分段上传，分段沿用开始时的分片大小、副本数和写入法定数
+func (c *Client) OpenMultipartUpload(ctx context.Context, id string) (*MultipartUpload, error) {
+	info, err := c.getUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Multipart {
+		return nil, &fs.PathError{Op: "multipart", Path: info.Path, Err: fs.ErrInvalid}
+	}
+	dir, _ := metadata.SplitPath(info.Path)
+	options, err := c.putOptions(ctx, dir, []PutOption{
+		WithChunkSize(info.ChunkSize),
+		WithReplicas(int(info.Replicas)),
+		WithWriteQuorum(int(info.WriteQuorum)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	options.multipart = true
+	return &MultipartUpload{c: c, ID: info.UploadId, Path: info.Path, opts: options}, nil
+}
+
+// 写入编号为 number（从 1 开始）的分段并提交，同一编号再次写入时替换之前的分段
+// 写入失败时已写入的分片在完成或放弃分段上传时回收
+func (u *MultipartUpload) UploadPart(ctx context.Context, number int, r io.Reader) (*Part, error) {
+	if number < 1 {
+		return nil, &fs.PathError{Op: "upload part", Path: u.Path, Err: fs.ErrInvalid}
+	}
+	w := &Writer{
+		c:        u.c,
+		ctx:      ctx,
+		path:     u.Path,
+		fileID:   newFileID(u.Path),
+		uploadID: u.ID,
+		part:     number,
+		opts:     u.opts,
+		unlock:   func() {}, // 分段可能来自不同客户端，只在完成时持有排他租约
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return nil, w.abort(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return w.result, nil
+}
+
+// 已提交的分段，按编号排序
+func (u *MultipartUpload) Parts(ctx context.Context) ([]Part, error) {
+	info, err := u.c.getUpload(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return uploadFromProto(info).Parts, nil
+}
+
+// 按编号顺序拼接 parts 指定的分段（须递增）并提交为文件，不指定时使用全部已提交的分段
+// 未使用的分段在后台回收
+func (u *MultipartUpload) Complete(ctx context.Context, parts ...int) (*Entry, error) {
+	unlock, err := u.c.lockForWrite(ctx, "complete", u.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	req := &metapb.CompleteMultipartRequest{UploadId: u.ID}
+	for _, number := range parts {
+		req.PartNumbers = append(req.PartNumbers, int32(number))
+	}
+	var resp *metapb.FileMetadata
+	err = u.c.callMeta(ctx, false, func(ctx context.Context) (err error) {
+		resp, err = u.c.meta.CompleteMultipartUpload(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, uploadError("complete", u.Path, err)
+	}
+	return &Entry{Path: u.Path, FileMetadata: metadata.FileMetadataFromProto(resp)}, nil
+}
+
+// 放弃分段上传，元数据服务回收全部分段的分片
+func (u *MultipartUpload) Abort(ctx context.Context) error {
+	return u.c.abortUpload(ctx, u.Path, u.ID)
+}
+
+// 登记分段写入 partID 中已写入的分片
+func (c *Client) addPartChunk(ctx context.Context, path, id, partID string, number int, chunk metadata.FileChunk) error {
+	err := c.callMeta(ctx, true, func(ctx context.Context) error {
+		_, err := c.meta.AddChunk(ctx, &metapb.AddChunkRequest{UploadId: id, Chunk: chunk.ToProto(), PartId: partID, PartNumber: int32(number)})
+		return err
+	})
+	return uploadError("upload part", path, err)
+}
+
+// 提交分段写入 partID，chunks 为其分片数
+func (c *Client) commitPart(ctx context.Context, path, id, partID string, number int, size int64, chunks int) (*Part, error) {
+	var resp *metapb.PartInfo
+	err := c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+		resp, err = c.meta.CommitPart(ctx, &metapb.CommitPartRequest{UploadId: id, PartId: partID, PartNumber: int32(number), Size: size, Chunks: int32(chunks)})
+		return err
+	})
+	if err != nil {
+		return nil, uploadError("upload part", path, err)
+	}
+	part := partFromProto(resp)
+	return &part, nil
+}
+
+// 获取未提交的上传
+func (c *Client) getUpload(ctx context.Context, id string) (*metapb.UploadInfo, error) {
+	var info *metapb.UploadInfo
+	err := c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+		info, err = c.meta.GetUpload(ctx, &metapb.UploadRequest{UploadId: id})
+		return err
+	})
+	if err != nil {
+		return nil, uploadError("multipart", id, err)
+	}
+	return info, nil
+}
@@ -0,0 +1,249 @@
+package dfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+)
+
+// 上传本地文件到 dst，dst 为已存在的目录时上传到该目录下
+// 所有分片写入后才提交为文件，提交前文件不可见；指定 WithReplace 时原子地替换已存在的文件
+// 全零的分片记为空洞，不占用存储
+// 上传中断后在有效期内再次上传同一文件到同一位置时，跳过已上传完成的分片
+func (c *Client) Put(ctx context.Context, localPath, dst string, opts ...PutOption) (*Entry, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "put", Path: localPath, Err: ErrIsDir}
+	}
+	fileSize := info.Size()
+
+	target := metadata.CleanPath(dst)
+	entry, err := c.Stat(ctx, target)
+	if err == nil && entry.IsDirectory {
+		target = joinPath(target, filepath.Base(localPath))
+		entry, err = c.Stat(ctx, target)
+	}
+	dir, filename := metadata.SplitPath(target)
+	options, optErr := c.putOptions(ctx, dir, opts)
+	// 目标已存在时在上传分片前失败，避免留下孤儿分片
+	switch {
+	case err == nil && entry.IsDirectory:
+		return nil, &fs.PathError{Op: "put", Path: target, Err: ErrIsDir}
+	case err == nil && !options.replace:
+		return nil, &fs.PathError{Op: "put", Path: target, Err: fs.ErrExist}
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	case optErr != nil:
+		return nil, optErr
+	}
+	existing := entry // 被替换的文件，不存在时为 nil
+	unlock, err := c.lockForWrite(ctx, "put", target)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	chunkSize := options.chunkSize
+	var fileChunks []metadata.FileChunk
+	var tasks []transferTask
+
+	// 读取上次中断的上传进度，续传时沿用上次的文件标识符，使分片 ID 保持一致
+	state, err := openUploadState(localPath, uploadHeader{Target: target, Size: fileSize, ModTime: info.ModTime(), ChunkSize: chunkSize})
+	if err != nil {
+		return nil, fmt.Errorf("open upload state: %w", err)
+	}
+	completed := false
+	defer func() { state.Close(completed) }()
+	// 上次的上传已提交但进度文件未删除：目标文件正使用这些分片，不能沿用其文件标识符
+	if state.Resumed() > 0 && existing != nil && usesFileID(existing, state.FileID()) {
+		if err := state.Reset(); err != nil {
+			return nil, fmt.Errorf("reset upload state: %w", err)
+		}
+	}
+	if state.Resumed() > 0 {
+		c.logf("Resuming upload: %d chunk(s) recorded as uploaded.\n", state.Resumed())
+	}
+	fileID := state.FileID() // 唯一文件标识符，同时作为上传 ID
+	if err := c.beginUpload(ctx, target, fileID, options, nil, 0); err != nil {
+		return nil, err
+	}
+
+	// 分片逻辑：分片数据在上传时才从本地文件读取，内存占用与并发数成正比
+	for i := int64(0); i < fileSize; i += chunkSize {
+		end := min(i+chunkSize, fileSize)
+		offset, size := i, end-i
+		chunkNumber := int(i / chunkSize)
+		chunkID := fmt.Sprintf("%s_%d", fileID, chunkNumber) // 唯一分片标识符
+
+		locations, err := c.cluster.place(chunkNumber, chunkID, options.replicas)
+		if err != nil {
+			return nil, err
+		}
+
+		fileChunks = append(fileChunks, metadata.FileChunk{
+			ChunkID:         chunkID,
+			FileID:          fileID,
+			ChunkNumber:     chunkNumber,
+			OriginalName:    filename,
+			Size:            size,
+			StorageLocation: locations[0],  // 主副本
+			Replicas:        locations[1:], // 其余副本
+		})
+
+		// 流水线上传：只发送给主副本，由节点依次转发给其余副本
+		tasks = append(tasks, transferTask{nodes: locations, run: func() error {
+			if checksum, ok := state.Uploaded(ctx, c.cluster, chunkNumber, chunkID, locations); ok {
+				fileChunks[chunkNumber].Checksum = checksum
+				c.logf("Skipped chunk %d, already on nodes %v.\n", chunkNumber, locations)
+				return c.addChunk(ctx, target, fileID, fileChunks[chunkNumber])
+			}
+
+			chunkData := make([]byte, size)
+			if _, err := file.ReadAt(chunkData, offset); err != nil {
+				return fmt.Errorf("read chunk %d: %w", chunkNumber, err)
+			}
+			if isZero(chunkData) {
+				// 与 Writer 相同，全零的分片记为空洞，不写入存储节点
+				chunk := &fileChunks[chunkNumber]
+				chunk.ChunkID, chunk.StorageLocation, chunk.Replicas = "", "", nil
+				c.logf("Recorded chunk %d as a hole.\n", chunkNumber)
+				return c.addChunk(ctx, target, fileID, *chunk)
+			}
+			checksum := metadata.Checksum(chunkData)
+
+			acked, pending, err := c.cluster.writeReplicas(ctx, locations, chunkID, chunkData, checksum, options.writeQuorum)
+			if err != nil {
+				return &ChunkError{Op: "upload", ChunkNumber: chunkNumber, Nodes: locations, Err: err}
+			}
+			chunk := &fileChunks[chunkNumber]
+			chunk.Checksum = checksum
+			chunk.StorageLocation, chunk.Replicas, chunk.Pending = acked[0], acked[1:], pending
+			if err := c.addChunk(ctx, target, fileID, *chunk); err != nil {
+				return err
+			}
+			// 只有全部副本写入的分片才记录为已上传，续传时重新写入其余分片
+			if len(pending) == 0 {
+				if err := state.Record(chunkNumber, checksum); err != nil {
+					c.logf("Failed to record upload progress: %v\n", err)
+				}
+				c.logf("Uploaded chunk %d to nodes %v successfully.\n", chunkNumber, acked)
+			} else {
+				c.logf("Uploaded chunk %d to nodes %v, pending repair on %v.\n", chunkNumber, acked, pending)
+			}
+			return nil
+		}})
+	}
+
+	// 并发上传所有分片
+	if err := c.cluster.runTransfers(tasks); err != nil {
+		return nil, err
+	}
+
+	// 所有分片已登记，提交为文件
+	entry, err = c.commitUpload(ctx, target, fileID, fileSize, len(fileChunks), len(fileChunks))
+	if err != nil {
+		return nil, err
+	}
+	completed = true
+	return entry, nil
+}
+
+// 文件是否引用了以 fileID 写入的分片
+func usesFileID(entry *Entry, fileID string) bool {
+	return slices.ContainsFunc(entry.Chunks, func(chunk metadata.FileChunk) bool { return chunk.FileID == fileID })
+}
+
+// 根据分片 ID 选出 replicas 个位于不同故障域的存储节点，第一个为主副本
+func (c *cluster) place(chunkNumber int, chunkID string, replicas int) ([]string, error) {
+	locations := c.placement.Place(chunkID, replicas)
+	if len(locations) < replicas {
+		return nil, &ChunkError{Op: "upload", ChunkNumber: chunkNumber, Nodes: locations, Err: ErrNotEnoughNodes}
+	}
+	return locations, nil
+}
+
+// 流水线写入时每条消息携带的最大数据量
+const pipelinePieceSize = 64 * 1024
+
+// 写入分片的副本，至少 quorum 个副本确认后才算成功，checksum 为分片数据的校验值，由每个副本写入前校验
+// 主副本按重试策略重试后仍失败时跳过它，由下一个节点作为流水线的起点；返回确认的节点和等待后台修复的节点
+func (c *cluster) writeReplicas(ctx context.Context, locations []string, chunkID string, data []byte, checksum string, quorum int) (acked, pending []string, err error) {
+	var errs []error
+	timeout := c.retry.chunkTimeout(int64(len(data)))
+	for start := 0; len(locations)-start >= quorum; start++ {
+		err = c.call(ctx, locations[start], timeout, func(ctx context.Context, head *storageNode) (err error) {
+			acked, err = c.pipelineWrite(ctx, head, locations[start:], chunkID, data, checksum)
+			return err
+		})
+		if err == nil {
+			break
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", locations[start], err))
+	}
+	if len(acked) < quorum {
+		errs = append(errs, fmt.Errorf("%w: %d of %d replicas acknowledged, need %d", ErrWriteQuorum, len(acked), len(locations), quorum))
+		return nil, nil, errors.Join(errs...)
+	}
+	for _, nodeID := range locations {
+		if !slices.Contains(acked, nodeID) {
+			pending = append(pending, nodeID)
+		}
+	}
+	return acked, pending, nil
+}
+
+// 将分片写入 locations[0]（即 head），并由其沿 locations 依次转发，返回确认写入的节点
+// 下游节点失败时只是不出现在返回值中，head 失败时返回错误
+func (c *cluster) pipelineWrite(ctx context.Context, head *storageNode, locations []string, chunkID string, data []byte, checksum string) ([]string, error) {
+	var downstream []string
+	byAddr := make(map[string]string)
+	for _, nodeID := range locations[1:] {
+		node, err := c.node(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		downstream = append(downstream, node.Addr)
+		byAddr[node.Addr] = nodeID
+	}
+
+	stream, err := head.PipelineWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req := &pb.PipelineWriteRequest{Filename: chunkID, Downstream: downstream, Checksum: checksum}
+	for offset := 0; ; offset += pipelinePieceSize {
+		req.Data = data[offset:min(offset+pipelinePieceSize, len(data))]
+		if err := stream.Send(req); err != nil {
+			break // 真正的错误由 CloseAndRecv 返回
+		}
+		if offset+pipelinePieceSize >= len(data) {
+			break
+		}
+		req = &pb.PipelineWriteRequest{}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, err
+	}
+	acked := []string{locations[0]}
+	for _, addr := range resp.Acked {
+		if nodeID, exists := byAddr[addr]; exists {
+			acked = append(acked, nodeID)
+		}
+	}
+	return acked, nil
+}
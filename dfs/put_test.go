@@ -0,0 +1,132 @@
+package dfs
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"grpc-distributed-fs/metadata"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPutWriteQuorum(t *testing.T) {
+	tests := []struct {
+		name    string
+		quorum  int
+		down    []int // 写入失败的节点下标
+		wantErr error
+	}{
+		{name: "all replicas", quorum: 2},
+		{name: "one replica down", quorum: 2, down: []int{1}},
+		{name: "primary candidates down", quorum: 1, down: []int{0, 2}},
+		{name: "below quorum", quorum: 2, down: []int{0, 2}, wantErr: ErrWriteQuorum},
+		{name: "all down", quorum: 1, down: []int{0, 1, 2}, wantErr: ErrWriteQuorum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir()) // 上传进度文件
+			fc := newFakeClusterOf(t, 3)
+			var down []string
+			for _, i := range tt.down {
+				fc.nodes[i].setFail("", status.Error(codes.Unavailable, "node down"))
+				down = append(down, fc.nodes[i].id)
+			}
+			data := bytes.Repeat([]byte("0123456789"), 2)
+			local := filepath.Join(t.TempDir(), "f")
+			if err := os.WriteFile(local, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			entry, err := fc.Put(context.Background(), local, "/f", WithChunkSize(8), WithWriteQuorum(tt.quorum))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Put = %v, want %v", err, tt.wantErr)
+				}
+				if _, err := fc.meta.tree.Lookup("/f"); err == nil {
+					t.Error("file committed without a write quorum")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entry.Chunks) != 3 {
+				t.Fatalf("%d chunks, want 3", len(entry.Chunks))
+			}
+			for _, chunk := range entry.Chunks {
+				locations := chunk.Locations()
+				if len(locations) != 3-len(down) {
+					t.Errorf("chunk %d on %v, want %d replicas", chunk.ChunkNumber, locations, 3-len(down))
+				}
+				pending := slices.Sorted(slices.Values(chunk.Pending))
+				if !slices.Equal(pending, down) {
+					t.Errorf("chunk %d pending %v, want %v", chunk.ChunkNumber, pending, down)
+				}
+				for _, node := range fc.nodes {
+					node.mu.Lock()
+					_, stored := node.chunks[chunk.ChunkID]
+					node.mu.Unlock()
+					if stored != slices.Contains(locations, node.id) {
+						t.Errorf("chunk %d stored on %s = %v, locations %v", chunk.ChunkNumber, node.id, stored, locations)
+					}
+				}
+			}
+		})
+	}
+}
+
+// 分片只发送给第一个节点，由其转发给其余节点，返回的确认按流水线顺序映射为节点 ID
+func TestPipelineWrite(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 2*pipelinePieceSize+1)
+	tests := []struct {
+		name      string
+		locations []string
+		down      string // 写入失败的节点
+		checksum  string
+		wantAcked []string
+		wantCode  codes.Code
+	}{
+		{name: "all replicas", locations: []string{"node2", "node1", "node3"}, wantAcked: []string{"node2", "node1", "node3"}},
+		{name: "downstream down", locations: []string{"node1", "node2", "node3"}, down: "node2", wantAcked: []string{"node1", "node3"}},
+		{name: "head down", locations: []string{"node1", "node2"}, down: "node1", wantCode: codes.Unavailable},
+		{name: "checksum mismatch", locations: []string{"node1", "node2"}, checksum: metadata.Checksum([]byte("other")), wantCode: codes.DataLoss},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newFakeClusterOf(t, 3)
+			for _, node := range fc.nodes {
+				if node.id == tt.down {
+					node.setFail("", status.Error(codes.Unavailable, "node down"))
+				}
+			}
+			checksum := cmp.Or(tt.checksum, metadata.Checksum(data))
+
+			head, err := fc.cluster.node(tt.locations[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			acked, err := fc.cluster.pipelineWrite(context.Background(), head, tt.locations, "c", data, checksum)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("pipelineWrite = %v, want %v", err, tt.wantCode)
+			}
+			if !slices.Equal(acked, tt.wantAcked) {
+				t.Errorf("acked %v, want %v", acked, tt.wantAcked)
+			}
+			for _, node := range fc.nodes {
+				node.mu.Lock()
+				got, stored := node.chunks["c"]
+				node.mu.Unlock()
+				if stored != slices.Contains(tt.wantAcked, node.id) || stored && !bytes.Equal(got, data) {
+					t.Errorf("%s stored %d bytes, acked %v", node.id, len(got), tt.wantAcked)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package dfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 一次记录追加最多尝试的轮数，每轮可能因分片已满、副本故障或租约失效而换用新的租约
+const appendRounds = 8
+
+// 将 record 作为一条记录原子地追加到文件末尾，返回记录在文件中的偏移
+// 多个客户端可以同时追加同一文件：元数据服务为文件授予追加租约，由租约分片的主副本依次决定记录的偏移，
+// 记录之间不会交错，也不会跨越分片（放不下时分片被填充到上限，记录写入新分片）
+// 失败重试可能使同一记录在文件中出现多次；文件须已存在，记录不能超过文件的分片大小
+func (c *Client) AppendRecord(ctx context.Context, path string, record []byte) (int64, error) {
+	path = metadata.CleanPath(path)
+	if len(record) == 0 {
+		return 0, &fs.PathError{Op: "append", Path: path, Err: fs.ErrInvalid}
+	}
+	var change *metapb.AppendLeaseRequest // 非 nil 时需要元数据服务更换分片
+	avoid := map[string]bool{}            // 本次追加中写入失败的节点，新分片尽量避开
+	var lastErr error
+	for range appendRounds {
+		lease, err := c.appendLease(ctx, path, change, avoid)
+		if err != nil {
+			return 0, err
+		}
+		change = nil
+		if int64(len(record)) > lease.MaxSize {
+			return 0, &fs.PathError{Op: "append", Path: path, Err: ErrRecordTooLarge}
+		}
+
+		chunk := metadata.FileChunkFromProto(lease.Chunk)
+		resp, err := c.appendToPrimary(ctx, chunk, lease, record)
+		switch {
+		case status.Code(err) == codes.FailedPrecondition:
+			// 租约已过期，重新申请
+			c.dropLease(path)
+			lastErr = pathError("append", path, err)
+			continue
+		case errors.Is(err, ErrCircuitOpen) || nodeFailure(err):
+			// 主副本无法写入，换用新分片
+			avoid[chunk.StorageLocation] = true
+			change = &metapb.AppendLeaseRequest{FailedChunkId: chunk.ChunkID}
+			lastErr = &ChunkError{Op: "append", ChunkNumber: chunk.ChunkNumber, Nodes: chunk.Locations(), Err: err}
+			continue
+		case err != nil:
+			return 0, &ChunkError{Op: "append", ChunkNumber: chunk.ChunkNumber, Nodes: chunk.Locations(), Err: err}
+		case resp.FailedSecondary != "":
+			// 其余副本无法写入，换用新分片
+			for _, nodeID := range chunk.Replicas {
+				if node, err := c.cluster.node(nodeID); err == nil && node.Addr == resp.FailedSecondary {
+					avoid[nodeID] = true
+				}
+			}
+			change = &metapb.AppendLeaseRequest{FailedChunkId: chunk.ChunkID}
+			lastErr = &ChunkError{Op: "append", ChunkNumber: chunk.ChunkNumber, Nodes: chunk.Locations(), Err: fmt.Errorf("replica %s unavailable", resp.FailedSecondary)}
+			continue
+		case resp.Full:
+			change = &metapb.AppendLeaseRequest{Full: &metapb.CommitRecordRequest{Path: path, ChunkId: chunk.ChunkID, Size: resp.Size, Checksum: resp.Checksum}}
+			lastErr = &ChunkError{Op: "append", ChunkNumber: chunk.ChunkNumber, Nodes: chunk.Locations(), Err: errors.New("chunk full")}
+			continue
+		}
+
+		var commit *metapb.CommitRecordResponse
+		err = c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+			commit, err = c.meta.CommitRecord(ctx, &metapb.CommitRecordRequest{Path: path, ChunkId: chunk.ChunkID, Size: resp.Size, Checksum: resp.Checksum})
+			return err
+		})
+		if status.Code(err) == codes.Aborted {
+			// 分片已不再接受追加（文件被替换或元数据服务重启），记录留在未引用的分片中，由垃圾回收清理
+			c.dropLease(path)
+			lastErr = pathError("append", path, err)
+			continue
+		}
+		if err != nil {
+			return 0, pathError("append", path, err)
+		}
+		return commit.ChunkOffset + resp.Offset, nil
+	}
+	return 0, lastErr
+}
+
+// 获取文件的追加租约：不需要更换分片时使用缓存中仍然有效的租约
+// 向元数据服务申请时附带按放置策略选择的新分片，供其在需要新分片时使用，新分片尽量避开 avoid 中和熔断中的节点
+func (c *Client) appendLease(ctx context.Context, path string, change *metapb.AppendLeaseRequest, avoid map[string]bool) (*metapb.AppendLease, error) {
+	if change == nil {
+		c.leaseMu.Lock()
+		lease := c.leases[path]
+		c.leaseMu.Unlock()
+		// 留出一次 RPC 的时间，避免租约在到达主副本前过期
+		if lease != nil && time.Until(time.Unix(0, lease.ExpiresAt)) > c.cluster.retry.rpcTimeout {
+			return lease, nil
+		}
+		change = &metapb.AppendLeaseRequest{}
+	}
+
+	entry, opts, err := c.openForUpdate(ctx, "append", path)
+	if err != nil {
+		return nil, err
+	}
+	// 放置位置由分片标识符决定，换用新的标识符直到避开故障节点，都避不开时使用最后一次的位置
+	var fileID, chunkID string
+	var locations []string
+	number := len(entry.Chunks)
+	for range appendRounds {
+		fileID = newFileID(entry.Path)
+		chunkID = fmt.Sprintf("%s_%d", fileID, number)
+		if locations, err = c.cluster.place(number, chunkID, opts.replicas); err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(locations, func(nodeID string) bool { return avoid[nodeID] || !c.cluster.available(nodeID) }) {
+			break
+		}
+	}
+	_, filename := metadata.SplitPath(entry.Path)
+	proposed := metadata.FileChunk{
+		ChunkID:         chunkID,
+		FileID:          fileID,
+		ChunkNumber:     number,
+		OriginalName:    filename,
+		StorageLocation: locations[0], // 主副本
+		Replicas:        locations[1:],
+	}
+	change.Path = path
+	change.Proposed = proposed.ToProto()
+	change.ChunkSize = opts.chunkSize
+
+	var lease *metapb.AppendLease
+	err = c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+		lease, err = c.meta.GrantAppendLease(ctx, change)
+		return err
+	})
+	if err != nil {
+		return nil, pathError("append", path, err)
+	}
+	c.leaseMu.Lock()
+	c.leases[path] = lease
+	c.leaseMu.Unlock()
+	return lease, nil
+}
+
+func (c *Client) dropLease(path string) {
+	c.leaseMu.Lock()
+	delete(c.leases, path)
+	c.leaseMu.Unlock()
+}
+
+// 将记录发给租约分片的主副本，由它决定偏移并写入其余副本
+// 只尝试一次，失败后由 AppendRecord 决定是否换用新分片
+func (c *Client) appendToPrimary(ctx context.Context, chunk metadata.FileChunk, lease *metapb.AppendLease, record []byte) (*pb.AppendRecordResponse, error) {
+	var secondaries []string
+	for _, nodeID := range chunk.Replicas {
+		node, err := c.cluster.node(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		secondaries = append(secondaries, node.Addr)
+	}
+	req := &pb.AppendRecordRequest{
+		Filename:       chunk.ChunkID,
+		Data:           record,
+		Secondaries:    secondaries,
+		MaxSize:        lease.MaxSize,
+		LeaseExpiresAt: lease.ExpiresAt,
+	}
+	// 主副本依次写入其余副本，超时时间按副本数放宽
+	timeout := c.cluster.retry.chunkTimeout(int64(len(record))) * time.Duration(len(chunk.Locations()))
+	var resp *pb.AppendRecordResponse
+	err := attemptOnce(ctx, timeout, c.cluster.guard(chunk.StorageLocation, func(ctx context.Context, node *storageNode) (err error) {
+		resp, err = node.AppendRecord(ctx, req)
+		return err
+	}))
+	return resp, err
+}
@@ -0,0 +1,205 @@
+package dfs
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+)
+
+// 上传进度文件的首行，用于判断本地文件和目标是否与上次相同
+type uploadHeader struct {
+	Target    string
+	Size      int64
+	ModTime   time.Time
+	ChunkSize int64
+	FileID    string // 上次生成的文件标识符，续传时沿用
+}
+
+// 上传进度文件中的一条记录：一个已上传完成的分片
+type uploadRecord struct {
+	ChunkNumber int
+	Checksum    string
+}
+
+// 上传进度：首行为 uploadHeader，之后每上传完成一个分片追加一行 uploadRecord
+type uploadState struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	done   map[int]string // 上次已上传的分片编号 -> 校验值
+	header uploadHeader
+}
+
+// 进度文件保存在用户缓存目录下，按本地文件和目标路径区分
+func uploadStatePath(localPath, target string) (string, error) {
+	abs, err := filepath.Abs(localPath)
+	if err != nil {
+		return "", err
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs + "\x00" + target))
+	return filepath.Join(dir, "dfs", "uploads", hex.EncodeToString(sum[:8])+".jsonl"), nil
+}
+
+// 打开上传进度文件，本地文件或分片大小变化时丢弃旧进度
+func openUploadState(localPath string, header uploadHeader) (*uploadState, error) {
+	path, err := uploadStatePath(localPath, header.Target)
+	if err != nil {
+		return nil, err
+	}
+	header.FileID = newFileID(header.Target)
+	state := &uploadState{path: path, done: make(map[int]string), header: header}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		var old uploadHeader
+		if scanner.Scan() && json.Unmarshal(scanner.Bytes(), &old) == nil &&
+			old.Target == header.Target && old.Size == header.Size &&
+			old.ModTime.Equal(header.ModTime) && old.ChunkSize == header.ChunkSize && old.FileID != "" {
+			state.header.FileID = old.FileID
+			for scanner.Scan() {
+				var record uploadRecord
+				if json.Unmarshal(scanner.Bytes(), &record) == nil {
+					state.done[record.ChunkNumber] = record.Checksum
+				}
+			}
+		}
+		f.Close()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	state.file, err = os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	// 重写进度文件，保留仍然有效的记录
+	if err := state.append(state.header); err != nil {
+		return nil, err
+	}
+	for chunkNumber, checksum := range state.done {
+		if err := state.append(uploadRecord{chunkNumber, checksum}); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+func (s *uploadState) append(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// 记录一个已上传完成的分片
+func (s *uploadState) Record(chunkNumber int, checksum string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.append(uploadRecord{chunkNumber, checksum})
+}
+
+// 上次已上传且所有副本节点上校验值一致的分片可以跳过
+func (s *uploadState) Uploaded(ctx context.Context, c *cluster, chunkNumber int, chunkID string, locations []string) (string, bool) {
+	checksum, exists := s.done[chunkNumber]
+	if !exists {
+		return "", false
+	}
+	for _, nodeID := range locations {
+		var resp *pb.StatResponse
+		err := c.call(ctx, nodeID, c.retry.rpcTimeout, func(ctx context.Context, node *storageNode) (err error) {
+			resp, err = node.StatFile(ctx, &pb.StatRequest{Filename: chunkID})
+			return err
+		})
+		if err != nil || !resp.Exists || resp.Checksum != checksum {
+			return "", false
+		}
+	}
+	return checksum, true
+}
+
+// 文件标识符：目标路径加随机后缀，文件被移动后在原路径重新上传也不会与其分片 ID 冲突
+func newFileID(target string) string {
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	return target + "#" + hex.EncodeToString(suffix)
+}
+
+// 放弃上次的进度，换用新的文件标识符重新上传全部分片
+func (s *uploadState) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.header.FileID = newFileID(s.header.Target)
+	s.done = make(map[int]string)
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.append(s.header)
+}
+
+// 本次上传使用的文件标识符
+func (s *uploadState) FileID() string {
+	return s.header.FileID
+}
+
+// 上传已恢复的分片数
+func (s *uploadState) Resumed() int {
+	return len(s.done)
+}
+
+// 关闭进度文件，上传完成时删除
+func (s *uploadState) Close(completed bool) {
+	s.file.Close()
+	if completed {
+		os.Remove(s.path)
+	}
+}
+
+// 下载的中间文件，与目标文件位于同一目录，失败后保留以便续传
+func partialPath(filename string) string {
+	return filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".dfs-part")
+}
+
+// 中间文件中该分片的数据已完整且校验值一致时可以跳过
+func chunkPresent(f *os.File, offset int64, chunk metadata.FileChunk) bool {
+	if chunk.Checksum == "" {
+		return false
+	}
+	data := make([]byte, chunk.Size)
+	if _, err := f.ReadAt(data, offset); err != nil {
+		return false
+	}
+	return metadata.Checksum(data) == chunk.Checksum
+}
+
+// 将中间文件中 [offset, offset+size) 已有的数据清零，超出文件末尾的部分不写入，保持本地文件稀疏
+func zeroRange(f *os.File, offset, size int64) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if n := min(size, info.Size()-offset); n > 0 {
+		_, err = f.WriteAt(make([]byte, n), offset)
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package dfs
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (n *fakeNode) StatFile(ctx context.Context, req *pb.StatRequest) (*pb.StatResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	data, exists := n.chunks[req.Filename]
+	if !exists {
+		return &pb.StatResponse{}, nil
+	}
+	return &pb.StatResponse{Exists: true, Size: int64(len(data)), Checksum: metadata.Checksum(data)}, nil
+}
+
+// 第一次上传在最后一个分片失败后中断，再次上传时跳过已上传的分片，上次的进度失效时重新上传全部分片
+func TestPutResume(t *testing.T) {
+	const chunkSize = 8
+	data := []byte("aaaaaaaabbbbbbbbcccc") // 三个分片
+	tests := []struct {
+		name       string
+		change     func(t *testing.T, fc *fakeCluster, local, state string) // 两次上传之间的变化
+		opts       []PutOption
+		wantWrites int // 第二次上传写入的分片数
+	}{
+		{name: "resume", wantWrites: 1},
+		{name: "local file modified", change: func(t *testing.T, fc *fakeCluster, local, state string) {
+			later := time.Now().Add(time.Hour)
+			if err := os.Chtimes(local, later, later); err != nil {
+				t.Fatal(err)
+			}
+		}, wantWrites: 3},
+		{name: "chunk size changed", opts: []PutOption{WithChunkSize(4)}, wantWrites: 5},
+		{name: "state file corrupt", change: func(t *testing.T, fc *fakeCluster, local, state string) {
+			if err := os.WriteFile(state, []byte("not json\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, wantWrites: 3},
+		{name: "uploaded chunk lost", change: func(t *testing.T, fc *fakeCluster, local, state string) {
+			fc.node.mu.Lock()
+			defer fc.node.mu.Unlock()
+			for id := range fc.node.chunks {
+				if strings.HasSuffix(id, "_0") {
+					delete(fc.node.chunks, id)
+				}
+			}
+		}, wantWrites: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			fc := newFakeCluster(t)
+			local := filepath.Join(t.TempDir(), "f")
+			if err := os.WriteFile(local, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			state, err := uploadStatePath(local, "/f")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+
+			fc.node.setFail("_2", status.Error(codes.Internal, "disk full"))
+			if _, err := fc.Put(ctx, local, "/f", WithChunkSize(chunkSize)); err == nil {
+				t.Fatal("interrupted upload succeeded")
+			}
+			if _, err := os.Stat(state); err != nil {
+				t.Fatalf("state file after interrupted upload: %v", err)
+			}
+			fc.node.setFail("", nil)
+			if tt.change != nil {
+				tt.change(t, fc, local, state)
+			}
+			fc.node.mu.Lock()
+			before := fc.node.writes
+			fc.node.mu.Unlock()
+
+			opts := append([]PutOption{WithChunkSize(chunkSize)}, tt.opts...)
+			if _, err := fc.Put(ctx, local, "/f", opts...); err != nil {
+				t.Fatal(err)
+			}
+			fc.node.mu.Lock()
+			writes := fc.node.writes - before
+			fc.node.mu.Unlock()
+			if writes != tt.wantWrites {
+				t.Errorf("resumed upload wrote %d chunks, want %d", writes, tt.wantWrites)
+			}
+			got, err := fs.ReadFile(fc.FS(ctx), "f")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("contents = %q, want %q", got, data)
+			}
+			if _, err := os.Stat(state); !os.IsNotExist(err) {
+				t.Errorf("state file kept after successful upload: %v", err)
+			}
+		})
+	}
+}
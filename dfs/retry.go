@@ -0,0 +1,200 @@
+package dfs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 重试策略，零值字段使用 DefaultRetryPolicy 中的值
+type RetryPolicy struct {
+	MaxAttempts    int           // 每次操作的最大尝试次数（含第一次），1 表示不重试
+	InitialBackoff time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff     time.Duration // 重试等待时间的上限
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 4, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+// 存储节点熔断策略：连续 Threshold 次节点故障后，Cooldown 内不再向该节点发送请求
+// 零值字段使用 DefaultBreakerPolicy 中的值，Threshold 为负数时不熔断
+type BreakerPolicy struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+var DefaultBreakerPolicy = BreakerPolicy{Threshold: 5, Cooldown: 10 * time.Second}
+
+// 客户端的超时和重试设置，由元数据请求和分片传输共用
+type retrier struct {
+	RetryPolicy
+	rpcTimeout    time.Duration // 单次元数据 RPC 的超时时间，也是分片传输超时的基数
+	minThroughput int64         // 分片传输按不低于该吞吐量（字节/秒）估算超时时间
+}
+
+// 连接断开后按重试策略的退避时间重新建立连接，使重试时连接已恢复
+// gRPC 默认的重连间隔最长可达两分钟，期间的请求都会立即失败
+func (r *retrier) connectParams() grpc.DialOption {
+	return grpc.WithConnectParams(grpc.ConnectParams{
+		Backoff: backoff.Config{
+			BaseDelay:  r.InitialBackoff,
+			Multiplier: 2,
+			Jitter:     0.5,
+			MaxDelay:   r.MaxBackoff,
+		},
+		MinConnectTimeout: r.rpcTimeout,
+	})
+}
+
+// 分片传输的超时时间，随分片大小增长
+func (r *retrier) chunkTimeout(size int64) time.Duration {
+	return r.rpcTimeout + time.Duration(size)*time.Second/time.Duration(r.minThroughput)
+}
+
+// 执行 op，可重试的错误按指数退避加随机抖动重试，直到成功、次数用完或 ctx 结束
+// 每次尝试使用各自的超时时间 timeout，0 表示只受 ctx 限制
+// 非幂等操作只在请求未到达服务端（Unavailable）时重试
+func (r *retrier) do(ctx context.Context, timeout time.Duration, idempotent bool, op func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := attemptOnce(ctx, timeout, op)
+		if err == nil || attempt >= r.MaxAttempts || !retryable(err, idempotent) || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-time.After(r.backoff(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// 第 retry 次重试前的等待时间：从 InitialBackoff 开始每次翻倍，不超过 MaxBackoff，再在后一半内随机抖动
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxBackoff)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func attemptOnce(ctx context.Context, timeout time.Duration, op func(ctx context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return op(ctx)
+}
+
+// 错误是否值得重试：由多个错误合并而成时，所有错误都可重试才重试
+func retryable(err error, idempotent bool) bool {
+	for err != nil {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			errs := joined.Unwrap()
+			for _, err := range errs {
+				if !retryable(err, idempotent) {
+					return false
+				}
+			}
+			return len(errs) > 0
+		}
+		if s, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+			switch s.GRPCStatus().Code() {
+			case codes.Unavailable:
+				return true
+			case codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+				return idempotent
+			}
+			return false
+		}
+		err = errors.Unwrap(err)
+	}
+	return false
+}
+
+// 错误是否说明节点本身不可用，这类错误计入熔断
+func nodeFailure(err error) bool {
+	code := status.Code(err)
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
+
+// 单个存储节点的熔断器
+// 冷却结束后放行请求，成功则恢复，失败则立即再次熔断
+type breaker struct {
+	BreakerPolicy
+	node string
+
+	mu        sync.Mutex
+	failures  int // 连续节点故障次数
+	openUntil time.Time
+}
+
+// 熔断期间返回 ErrCircuitOpen
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// 记录一次请求的结果
+func (b *breaker) record(err error) {
+	if b.Threshold < 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !nodeFailure(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.Threshold {
+		if !time.Now().Before(b.openUntil) {
+			log.Printf("Storage node %s failed %d times in a row, pausing requests for %v", b.node, b.failures, b.Cooldown)
+		}
+		b.openUntil = time.Now().Add(b.Cooldown)
+	}
+}
+
+// 在节点 nodeID 上执行一次 RPC，熔断期间直接返回 ErrCircuitOpen
+func (c *cluster) guard(nodeID string, op func(ctx context.Context, node *storageNode) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		node, err := c.node(nodeID)
+		if err != nil {
+			return err
+		}
+		if err := node.breaker.allow(); err != nil {
+			return err
+		}
+		err = op(ctx, node)
+		node.breaker.record(err)
+		return err
+	}
+}
+
+// 节点是否可以接受请求：节点存在且不在熔断期间
+func (c *cluster) available(nodeID string) bool {
+	node, err := c.node(nodeID)
+	return err == nil && node.breaker.allow() == nil
+}
+
+// 在节点 nodeID 上执行 RPC，按重试策略重试，每次尝试的超时时间为 timeout
+func (c *cluster) call(ctx context.Context, nodeID string, timeout time.Duration, op func(ctx context.Context, node *storageNode) error) error {
+	return c.retry.do(ctx, timeout, true, c.guard(nodeID, op))
+}
+
+// 执行元数据 RPC，按重试策略重试，每次尝试的超时时间为 rpcTimeout
+func (c *Client) callMeta(ctx context.Context, idempotent bool, op func(ctx context.Context) error) error {
+	return c.cluster.retry.do(ctx, c.cluster.retry.rpcTimeout, idempotent, op)
+}
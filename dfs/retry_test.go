@@ -0,0 +1,171 @@
+package dfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		retry    int
+		min, max time.Duration // 抖动后的范围，max 为翻倍并封顶后的等待时间
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{4, 400 * time.Millisecond, 800 * time.Millisecond},
+		{5, 500 * time.Millisecond, time.Second},
+		{100, 500 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.retry), func(t *testing.T) {
+			lo, hi := time.Duration(1<<62), time.Duration(0)
+			for range 1000 {
+				d := policy.backoff(tt.retry)
+				lo, hi = min(lo, d), max(hi, d)
+			}
+			if lo < tt.min || hi > tt.max {
+				t.Errorf("backoff in [%v, %v], want within [%v, %v]", lo, hi, tt.min, tt.max)
+			}
+			// 抖动覆盖整个范围，而不是固定值
+			if spread := tt.max - tt.min; hi-lo < spread/2 {
+				t.Errorf("backoff in [%v, %v], want spread over [%v, %v]", lo, hi, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	tests := []struct {
+		name         string
+		err          error
+		idempotent   bool
+		wantAttempts int
+	}{
+		{"unavailable", unavailable, false, 4},
+		{"deadline exceeded, idempotent", status.Error(codes.DeadlineExceeded, ""), true, 4},
+		{"deadline exceeded, not idempotent", status.Error(codes.DeadlineExceeded, ""), false, 1},
+		{"resource exhausted, idempotent", status.Error(codes.ResourceExhausted, ""), true, 4},
+		{"aborted, not idempotent", status.Error(codes.Aborted, ""), false, 1},
+		{"invalid argument", status.Error(codes.InvalidArgument, ""), true, 1},
+		{"not found", status.Error(codes.NotFound, ""), true, 1},
+		{"data loss", status.Error(codes.DataLoss, ""), true, 1},
+		{"wrapped", fmt.Errorf("node1: %w", unavailable), false, 4},
+		{"joined, all retryable", errors.Join(unavailable, unavailable), false, 4},
+		{"joined with not found", errors.Join(unavailable, status.Error(codes.NotFound, "")), true, 1},
+		{"not a status", errors.New("local failure"), true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &retrier{RetryPolicy: RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}}
+			attempts := 0
+			err := r.do(context.Background(), 0, tt.idempotent, func(ctx context.Context) error {
+				attempts++
+				return tt.err
+			})
+			if err != tt.err {
+				t.Errorf("do = %v, want %v", err, tt.err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("%d attempts, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnSuccess(t *testing.T) {
+	r := &retrier{RetryPolicy: RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}}
+	attempts := 0
+	err := r.do(context.Background(), 0, false, func(ctx context.Context) error {
+		if attempts++; attempts < 3 {
+			return status.Error(codes.Unavailable, "")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("do = %v after %d attempts, want success after 3", err, attempts)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	failure := status.Error(codes.Unavailable, "")
+	type step struct {
+		wait     bool  // 先等待冷却结束
+		record   error // 记录的请求结果，wantOpen 在记录之后检查
+		success  bool  // 记录一次成功的请求
+		wantOpen bool
+	}
+	tests := []struct {
+		name      string
+		threshold int
+		steps     []step
+	}{
+		{"opens after threshold failures", 3, []step{
+			{record: failure},
+			{record: failure},
+			{record: failure, wantOpen: true},
+		}},
+		{"success resets the count", 3, []step{
+			{record: failure},
+			{record: failure},
+			{success: true},
+			{record: failure},
+			{record: failure},
+		}},
+		{"request errors are not node failures", 2, []step{
+			{record: failure},
+			{record: status.Error(codes.NotFound, "")},
+			{record: failure},
+		}},
+		{"half-open probe fails", 2, []step{
+			{record: failure},
+			{record: failure, wantOpen: true},
+			{wait: true},
+			{record: failure, wantOpen: true},
+		}},
+		{"half-open probe succeeds", 2, []step{
+			{record: failure},
+			{record: failure, wantOpen: true},
+			{wait: true},
+			{success: true},
+			{record: failure},
+			{record: failure, wantOpen: true},
+		}},
+		{"disabled", -1, []step{
+			{record: failure},
+			{record: failure},
+			{record: failure},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &breaker{BreakerPolicy: BreakerPolicy{Threshold: tt.threshold, Cooldown: cooldown}, node: "node1"}
+			for i, st := range tt.steps {
+				if st.wait {
+					time.Sleep(cooldown)
+					if err := b.allow(); err != nil {
+						t.Fatalf("step %d: allow after cooldown = %v", i, err)
+					}
+					continue
+				}
+				if st.success {
+					b.record(nil)
+				} else {
+					b.record(st.record)
+				}
+				if err := b.allow(); (err != nil) != st.wantOpen || err != nil && !errors.Is(err, ErrCircuitOpen) {
+					t.Fatalf("step %d: allow = %v, want open %v", i, err, st.wantOpen)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package dfs
+
+import "slices"
+
+// 默认并发限制
+const (
+	defaultParallel     = 8 // 整体同时进行的分片传输数
+	defaultNodeParallel = 2 // 单个节点同时进行的分片传输数
+)
+
+// 分片传输任务
+type transferTask struct {
+	nodes []string     // 传输涉及的节点（流水线中的全部副本、对冲读取的目标），各占用一个并发槽位，第一个决定所在队列
+	run   func() error // 执行传输
+}
+
+type transferResult struct {
+	nodes []string
+	err   error
+}
+
+// 并发执行传输任务，同时受整体和单节点并发限制
+// 任务按第一个节点分队列调度，某个节点变慢只会占满它自己的槽位，不会阻塞不涉及它的任务
+// 任务涉及的所有节点都有空闲槽位时才启动，并一次占用全部槽位，不会出现互相等待的部分占用
+// 任一任务失败后不再启动新任务，等待已启动的任务结束后返回第一个错误
+func (c *cluster) runTransfers(tasks []transferTask) error {
+	queues := make(map[string][]transferTask)
+	var order []string // 节点轮转顺序
+	for _, task := range tasks {
+		var node string // 不涉及节点的任务只受整体并发限制
+		if len(task.nodes) > 0 {
+			node = task.nodes[0]
+		}
+		if _, exists := queues[node]; !exists {
+			order = append(order, node)
+		}
+		queues[node] = append(queues[node], task)
+	}
+
+	done := make(chan transferResult)
+	running := make(map[string]int)
+	inflight, pending := 0, len(tasks)
+	var firstErr error
+	for pending > 0 || inflight > 0 {
+		// 轮流从各节点队列取任务，直到达到并发上限
+		for launched := true; launched && firstErr == nil && inflight < c.parallel; {
+			launched = false
+			for _, node := range order {
+				if inflight >= c.parallel {
+					break
+				}
+				if len(queues[node]) == 0 {
+					continue
+				}
+				task := queues[node][0]
+				if slices.ContainsFunc(task.nodes, func(node string) bool { return running[node] >= c.nodeParallel }) {
+					continue
+				}
+				queues[node] = queues[node][1:]
+				for _, node := range task.nodes {
+					running[node]++
+				}
+				inflight++
+				pending--
+				launched = true
+				go func() {
+					done <- transferResult{nodes: task.nodes, err: task.run()}
+				}()
+			}
+		}
+		if inflight == 0 {
+			break // 出错后剩余任务不再执行
+		}
+
+		result := <-done
+		for _, node := range result.nodes {
+			running[node]--
+		}
+		inflight--
+		if result.err != nil && firstErr == nil {
+			firstErr = result.err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,81 @@
+package dfs
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunTransfersLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		parallel     int
+		nodeParallel int
+		tasks        [][]string // 每个任务涉及的节点
+	}{
+		{"primary only", 8, 2, [][]string{{"a"}, {"a"}, {"a"}, {"b"}, {"b"}, {"c"}}},
+		{"pipeline replicas", 8, 1, [][]string{{"a", "b"}, {"b", "c"}, {"c", "a"}, {"a", "c"}, {"b", "a"}}},
+		{"hedged reads", 3, 2, [][]string{{"a", "b", "c"}, {"b", "c", "a"}, {"c", "a", "b"}, {"a", "b", "c"}}},
+		{"overall limit", 2, 4, [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{"no nodes", 2, 1, [][]string{{}, {}, {"a"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &cluster{parallel: tt.parallel, nodeParallel: tt.nodeParallel}
+			var mu sync.Mutex
+			running := make(map[string]int)
+			total, ran := 0, 0
+			var tasks []transferTask
+			for _, nodes := range tt.tasks {
+				tasks = append(tasks, transferTask{nodes: nodes, run: func() error {
+					mu.Lock()
+					total++
+					ran++
+					if total > tt.parallel {
+						t.Errorf("%d transfers running, limit %d", total, tt.parallel)
+					}
+					for _, node := range nodes {
+						running[node]++
+						if running[node] > tt.nodeParallel {
+							t.Errorf("%d transfers on node %s, limit %d", running[node], node, tt.nodeParallel)
+						}
+					}
+					mu.Unlock()
+
+					time.Sleep(5 * time.Millisecond)
+
+					mu.Lock()
+					total--
+					for _, node := range nodes {
+						running[node]--
+					}
+					mu.Unlock()
+					return nil
+				}})
+			}
+			if err := c.runTransfers(tasks); err != nil {
+				t.Fatal(err)
+			}
+			if ran != len(tasks) {
+				t.Fatalf("ran %d of %d tasks", ran, len(tasks))
+			}
+		})
+	}
+}
+
+func TestRunTransfersStopsAfterError(t *testing.T) {
+	c := &cluster{parallel: 1, nodeParallel: 1}
+	errFailed := errors.New("failed")
+	ran := 0
+	tasks := []transferTask{
+		{nodes: []string{"a"}, run: func() error { ran++; return errFailed }},
+		{nodes: []string{"a"}, run: func() error { ran++; return nil }},
+	}
+	if err := c.runTransfers(tasks); !errors.Is(err, errFailed) {
+		t.Fatalf("runTransfers = %v, want %v", err, errFailed)
+	}
+	if ran != 1 {
+		t.Fatalf("ran %d tasks after the first failure, want 1", ran)
+	}
+}
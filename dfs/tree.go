@@ -0,0 +1,139 @@
+package dfs
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"grpc-distributed-fs/metadata"
+)
+
+// 递归传输时同时传输的文件数，每个文件的分片另受 Config.Parallel 限制
+const treeParallel = 4
+
+// 递归传输中失败的文件
+type FileError struct {
+	Path string // 本地路径（上传）或目录树中的路径（下载）
+	Err  error
+}
+
+// 递归传输结果
+type TreeReport struct {
+	Files    int   // 传输成功的文件数
+	Bytes    int64 // 传输成功的字节数
+	Failures []FileError
+}
+
+// 将本地目录 localDir 上传为 dst，按本地层级创建目录并上传其中所有普通文件
+// 单个文件失败不会中止其余文件，失败记录在 TreeReport.Failures 中
+func (c *Client) PutDir(ctx context.Context, localDir, dst string, opts ...PutOption) (*TreeReport, error) {
+	dst = metadata.CleanPath(dst)
+	if err := c.MkdirAll(ctx, dst); err != nil {
+		return nil, err
+	}
+
+	// 先按层级创建目录，再并发上传文件
+	var jobs []treeJob
+	err := filepath.WalkDir(localDir, func(local string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, local)
+		if err != nil {
+			return err
+		}
+		target := joinPath(dst, filepath.ToSlash(rel))
+		switch {
+		case d.IsDir():
+			return c.MkdirAll(ctx, target)
+		case d.Type().IsRegular():
+			jobs = append(jobs, treeJob{local: local, remote: target})
+		}
+		return nil // 跳过符号链接等特殊文件
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.runTree(jobs, func(job treeJob) (*Entry, error) {
+		entry, err := c.Put(ctx, job.local, job.remote, opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.logf("Uploaded %s to %s.\n", job.local, job.remote)
+		return entry, nil
+	}, func(job treeJob) string { return job.local }), nil
+}
+
+// 将目录树中的目录 src 下载到本地目录 localDir，按层级创建本地目录并下载其中所有文件
+// 单个文件失败不会中止其余文件，失败记录在 TreeReport.Failures 中
+func (c *Client) GetDir(ctx context.Context, src, localDir string, opts ...GetOption) (*TreeReport, error) {
+	root, err := c.Stat(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	if !root.IsDirectory {
+		return nil, &fs.PathError{Op: "get", Path: root.Path, Err: ErrNotDir}
+	}
+
+	var jobs []treeJob
+	err = c.Walk(ctx, root.Path, func(entry *Entry) error {
+		rel := strings.TrimPrefix(strings.TrimPrefix(entry.Path, root.Path), "/")
+		local := filepath.Join(localDir, filepath.FromSlash(rel))
+		if entry.IsDirectory {
+			return os.MkdirAll(local, 0755)
+		}
+		jobs = append(jobs, treeJob{local: local, remote: entry.Path})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.runTree(jobs, func(job treeJob) (*Entry, error) {
+		entry, err := c.Get(ctx, job.remote, job.local, opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.logf("Downloaded %s to %s.\n", job.remote, job.local)
+		return entry, nil
+	}, func(job treeJob) string { return job.remote }), nil
+}
+
+// 递归传输中的一个文件
+type treeJob struct {
+	local  string
+	remote string
+}
+
+// 并发传输所有文件并汇总结果，name 返回失败记录中使用的路径
+func (c *Client) runTree(jobs []treeJob, transfer func(treeJob) (*Entry, error), name func(treeJob) string) *TreeReport {
+	report := &TreeReport{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, treeParallel)
+	for _, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			entry, err := transfer(job)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				report.Failures = append(report.Failures, FileError{Path: name(job), Err: err})
+				return
+			}
+			report.Files++
+			report.Bytes += entry.Size
+		}()
+	}
+	wg.Wait()
+	sort.Slice(report.Failures, func(i, j int) bool { return report.Failures[i].Path < report.Failures[j].Path })
+	return report
+}
@@ -0,0 +1,236 @@
+package dfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+
+	"grpc-distributed-fs/metadata"
+)
+
+// 在文件末尾追加 r 中的全部数据
+// 末尾不满一个分片的分片与新数据合并后重新写入，其余新数据写入新分片，提交时原子地替换原文件的末尾分片
+// 其间文件被其他客户端修改时返回 ErrConflict，原文件保持不变
+func (c *Client) Append(ctx context.Context, path string, r io.Reader) (*Entry, error) {
+	entry, opts, err := c.openForUpdate(ctx, "append", path)
+	if err != nil {
+		return nil, err
+	}
+	return c.extend(ctx, entry, opts, func(w *Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// 在文件末尾写入 write 产生的数据，末尾不满一个分片的分片重新写入
+func (c *Client) extend(ctx context.Context, entry *Entry, opts putOptions, write func(w *Writer) error) (*Entry, error) {
+	keep := len(entry.Chunks)
+	var tail []byte
+	if keep > 0 && entry.Chunks[keep-1].Size < opts.chunkSize { // 不满一个分片的空洞同样以零重新写入
+		keep--
+		data, err := c.fetchChunk(ctx, entry.Chunks[keep])
+		if err != nil {
+			return nil, err
+		}
+		tail = data
+	}
+	w, err := c.newWriter(ctx, entry.Path, opts, entry, keep)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(tail); err != nil {
+		return nil, w.abort(err)
+	}
+	if err := write(w); err != nil {
+		return nil, w.abort(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return w.entry, nil
+}
+
+// 将文件截断为 size 字节：丢弃之后的分片，并重新写入被截断的分片的剩余部分
+// size 大于文件大小时在末尾补零，补满末尾分片之后的零记为一个空洞，不占用存储
+func (c *Client) Truncate(ctx context.Context, path string, size int64) (*Entry, error) {
+	if size < 0 {
+		return nil, &fs.PathError{Op: "truncate", Path: path, Err: fs.ErrInvalid}
+	}
+	entry, opts, err := c.openForUpdate(ctx, "truncate", path)
+	if err != nil {
+		return nil, err
+	}
+	if size == entry.Size {
+		return entry, nil
+	}
+	if size > entry.Size {
+		return c.extend(ctx, entry, opts, func(w *Writer) error {
+			return w.writeZeros(size - entry.Size)
+		})
+	}
+
+	// 保留完全位于 size 之前的分片
+	keep, kept := 0, int64(0)
+	for keep < len(entry.Chunks) && kept+entry.Chunks[keep].Size <= size {
+		kept += entry.Chunks[keep].Size
+		keep++
+	}
+	w, err := c.newWriter(ctx, entry.Path, opts, entry, keep)
+	if err != nil {
+		return nil, err
+	}
+	if kept < size {
+		// 被截断的分片：空洞直接缩短，数据分片重新写入剩余部分
+		chunk := entry.Chunks[keep]
+		if chunk.IsHole() {
+			err = w.record(w.nextChunk(size - kept))
+		} else {
+			var data []byte
+			if data, err = c.fetchChunk(ctx, chunk); err == nil {
+				_, err = w.Write(data[:size-kept])
+			}
+		}
+		if err != nil {
+			return nil, w.abort(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return w.entry, nil
+}
+
+// 将 p 写入文件的 off 处，只重新写入与 [off, off+len(p)) 重叠的分片，提交时原子地替换这些分片
+// 写入范围超出文件末尾时文件随之增长，原末尾与 off 之间记为空洞；写入空洞时空洞被拆分，只写入覆盖的部分
+// 其间文件被其他客户端修改时返回 ErrConflict，原文件保持不变
+func (c *Client) WriteAt(ctx context.Context, path string, p []byte, off int64) (*Entry, error) {
+	if off < 0 {
+		return nil, &fs.PathError{Op: "pwrite", Path: path, Err: fs.ErrInvalid}
+	}
+	entry, opts, err := c.openForUpdate(ctx, "pwrite", path)
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return entry, nil
+	}
+	end := off + int64(len(p))
+	size := max(entry.Size, end)
+
+	// 第一个受影响的分片：包含 off 的分片，off 超出文件末尾时为最后一个不满的分片（它将增长）或新分片
+	first, start := 0, int64(0)
+	for ; first < len(entry.Chunks); first++ {
+		chunk := entry.Chunks[first]
+		last := first == len(entry.Chunks)-1
+		if off < start+chunk.Size || (last && !chunk.IsHole() && chunk.Size < opts.chunkSize) {
+			break
+		}
+		start += chunk.Size
+	}
+	w, err := c.newWriter(ctx, entry.Path, opts, entry, first)
+	if err != nil {
+		return nil, err
+	}
+	// 将 p 中位于 [from, to) 的数据按分片大小切分后写入
+	writeRange := func(from, to int64) error {
+		for ; from < to; from += opts.chunkSize {
+			n := min(opts.chunkSize, to-from)
+			if err := w.flush(p[from-off : from-off+n]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	hole := func(n int64) error {
+		if n <= 0 {
+			return nil
+		}
+		return w.record(w.nextChunk(n))
+	}
+
+	// 依次重写受影响的分片：数据分片保持原大小（最后一个分片可增长到分片大小），空洞拆分为前后两段空洞和写入的数据
+	i, pos := first, start
+	for ; pos < end; i++ {
+		if i == len(entry.Chunks) {
+			// 原末尾之后：先记录到 off 为止的空洞，再写入新分片
+			err = hole(off - pos)
+			if err == nil {
+				err = writeRange(max(pos, off), end)
+			}
+			if err != nil {
+				return nil, w.abort(err)
+			}
+			pos = end
+			break
+		}
+		chunk := entry.Chunks[i]
+		if chunk.IsHole() {
+			chunkEnd := pos + chunk.Size
+			err = hole(min(off, chunkEnd) - pos)
+			if err == nil {
+				err = writeRange(max(pos, off), min(end, chunkEnd))
+			}
+			if err == nil {
+				err = hole(chunkEnd - max(end, pos))
+			}
+			if err != nil {
+				return nil, w.abort(err)
+			}
+			pos = chunkEnd
+			continue
+		}
+		data, err := c.fetchChunk(ctx, chunk)
+		if err != nil {
+			return nil, w.abort(err)
+		}
+		if i == len(entry.Chunks)-1 {
+			if n := min(opts.chunkSize, size-pos); n > int64(len(data)) {
+				data = append(data, make([]byte, n-int64(len(data)))...)
+			}
+		}
+		if off < pos+int64(len(data)) {
+			copy(data[max(off-pos, 0):], p[max(pos-off, 0):])
+		}
+		if err := w.flush(data); err != nil {
+			return nil, w.abort(err)
+		}
+		pos += int64(len(data))
+	}
+	// 之后的分片沿用原文件
+	if i < len(entry.Chunks) {
+		w.tail, w.tailSize = len(entry.Chunks)-i, entry.Size-pos
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return w.entry, nil
+}
+
+// 获取要更新的文件及其写入选项：沿用文件记录的分片大小、副本数和写入法定数
+func (c *Client) openForUpdate(ctx context.Context, op, path string) (*Entry, putOptions, error) {
+	entry, err := c.Stat(ctx, path)
+	if err != nil {
+		return nil, putOptions{}, err
+	}
+	if entry.IsDirectory {
+		return nil, putOptions{}, &fs.PathError{Op: op, Path: entry.Path, Err: ErrIsDir}
+	}
+	dir, _ := metadata.SplitPath(entry.Path)
+	opts, err := c.putOptions(ctx, dir, []PutOption{
+		WithChunkSize(entry.ChunkSize),
+		WithReplicas(entry.Replicas),
+		WithWriteQuorum(entry.WriteQuorum),
+	})
+	if err != nil {
+		return nil, putOptions{}, err
+	}
+	// 未记录分片大小的文件：除最后一个分片外大小都相同
+	if entry.ChunkSize == 0 && len(entry.Chunks) > 0 {
+		if len(entry.Chunks) > 1 {
+			opts.chunkSize = entry.Chunks[0].Size
+		} else {
+			opts.chunkSize = max(opts.chunkSize, entry.Chunks[0].Size)
+		}
+	}
+	return entry, opts, nil
+}
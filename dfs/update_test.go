@@ -0,0 +1,296 @@
+package dfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 未提交的上传：保留原文件的前 Keep 个分片，提交时拼接原文件末尾沿用的分片
+type fakeUpload struct {
+	req    *metapb.BeginUploadRequest
+	chunks map[int]metadata.FileChunk
+}
+
+func (m *fakeMeta) GetDefaults(ctx context.Context, req *metapb.PathRequest) (*metapb.Defaults, error) {
+	return &metapb.Defaults{}, nil
+}
+
+func (m *fakeMeta) Lock(ctx context.Context, req *metapb.LockRequest) (*metapb.LockInfo, error) {
+	return &metapb.LockInfo{Path: req.Path}, nil
+}
+
+func (m *fakeMeta) Unlock(ctx context.Context, req *metapb.PathRequest) (*metapb.Empty, error) {
+	return &metapb.Empty{}, nil
+}
+
+func (m *fakeMeta) BeginUpload(ctx context.Context, req *metapb.BeginUploadRequest) (*metapb.UploadInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uploads[req.UploadId] = &fakeUpload{req: req, chunks: make(map[int]metadata.FileChunk)}
+	return &metapb.UploadInfo{UploadId: req.UploadId, Path: req.Path}, nil
+}
+
+func (m *fakeMeta) AddChunk(ctx context.Context, req *metapb.AddChunkRequest) (*metapb.UploadInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, exists := m.uploads[req.UploadId]
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "upload %s not found", req.UploadId)
+	}
+	chunk := metadata.FileChunkFromProto(req.Chunk)
+	u.chunks[chunk.ChunkNumber] = chunk
+	return &metapb.UploadInfo{UploadId: req.UploadId, Path: u.req.Path}, nil
+}
+
+func (m *fakeMeta) AbortUpload(ctx context.Context, req *metapb.UploadRequest) (*metapb.Empty, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.uploads, req.UploadId)
+	return &metapb.Empty{}, nil
+}
+
+// 按元数据服务的规则拼接分片：原文件的前 Keep 个分片、本次写入的分片、原文件末尾沿用的分片
+func (m *fakeMeta) CommitUpload(ctx context.Context, req *metapb.CommitUploadRequest) (*metapb.FileMetadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, exists := m.uploads[req.UploadId]
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "upload %s not found", req.UploadId)
+	}
+	keep, count, written := int(u.req.Keep), int(req.Chunks), int(req.Written)
+	if written == 0 {
+		written = count - keep
+	}
+	tail := keep + written
+	var old []metadata.FileChunk
+	if node, err := m.tree.Lookup(u.req.Path); err == nil {
+		old = node.Metadata.Chunks
+	}
+	if len(u.chunks) != written || keep > len(old) || len(old)-(count-tail) < keep {
+		return nil, status.Errorf(codes.FailedPrecondition, "bad chunk range: keep %d, %d written of %d recorded, %d in total", keep, written, len(u.chunks), count)
+	}
+
+	dir, name := metadata.SplitPath(u.req.Path)
+	meta := &metadata.FileMetadata{Name: name, ModificationTime: time.Now(), ChunkSize: u.req.ChunkSize, Replicas: int(u.req.Replicas)}
+	meta.Chunks = append(meta.Chunks, old[:keep]...)
+	for number := keep; number < tail; number++ {
+		chunk, exists := u.chunks[number]
+		if !exists {
+			return nil, status.Errorf(codes.FailedPrecondition, "chunk %d not recorded", number)
+		}
+		meta.Chunks = append(meta.Chunks, chunk)
+	}
+	meta.Chunks = append(meta.Chunks, old[len(old)-(count-tail):]...)
+	for i := range meta.Chunks {
+		meta.Chunks[i].ChunkNumber = i
+		meta.Size += meta.Chunks[i].Size
+	}
+	if meta.Size != req.Size {
+		return nil, status.Errorf(codes.FailedPrecondition, "%d of %d bytes recorded", meta.Size, req.Size)
+	}
+	if old != nil {
+		if err := m.tree.RemoveAt(u.req.Path); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.tree.AddFileAt(dir, meta); err != nil {
+		return nil, err
+	}
+	delete(m.uploads, req.UploadId)
+	return meta.ToProto(), nil
+}
+
+// 接收并校验分片后依次写入下游节点，跳过写入失败的节点，与存储节点的流水线写入相同
+func (n *fakeNode) PipelineWrite(stream pb.FileSystem_PipelineWriteServer) error {
+	var filename, checksum string
+	var downstream []string
+	var data []byte
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if filename == "" {
+			filename, downstream, checksum = req.Filename, req.Downstream, req.Checksum
+		}
+		data = append(data, req.Data...)
+	}
+	if checksum != metadata.Checksum(data) {
+		return status.Errorf(codes.DataLoss, "chunk %s: checksum mismatch", filename)
+	}
+	if err := n.store(filename, data); err != nil {
+		return err
+	}
+	var acked []string
+	for _, addr := range downstream {
+		if peer, exists := n.peers[addr]; exists && peer.store(filename, data) == nil {
+			acked = append(acked, addr)
+		}
+	}
+	return stream.SendAndClose(&pb.PipelineWriteResponse{Acked: acked})
+}
+
+func (n *fakeNode) store(filename string, data []byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.fail != nil && strings.HasSuffix(filename, n.failOnly) {
+		return n.fail
+	}
+	n.chunks[filename] = data
+	n.writes++
+	return nil
+}
+
+// 之后写入 ID 以 suffix 结尾的分片时返回 err，suffix 为空时写入任何分片都返回 err
+func (n *fakeNode) setFail(suffix string, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.fail, n.failOnly = err, suffix
+}
+
+// 文件的分片布局：=n 为沿用的原分片，+n 为重新写入的分片，hn 为空洞
+func (fc *fakeCluster) layout(t *testing.T, path string, before map[string]bool) string {
+	t.Helper()
+	node, err := fc.meta.tree.Lookup(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []string
+	for _, chunk := range node.Metadata.Chunks {
+		switch {
+		case chunk.IsHole():
+			parts = append(parts, fmt.Sprintf("h%d", chunk.Size))
+		case before[chunk.ChunkID]:
+			parts = append(parts, fmt.Sprintf("=%d", chunk.Size))
+		default:
+			parts = append(parts, fmt.Sprintf("+%d", chunk.Size))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestWriteAt(t *testing.T) {
+	const chunkSize = 8
+	data := func(n int) []byte { return bytes.Repeat([]byte("d"), n) }
+	hole := func(n int) []byte { return make([]byte, n) }
+	tests := []struct {
+		name   string
+		pieces [][]byte // 原文件的分片，全零的为空洞
+		p      []byte
+		off    int64
+		want   string
+	}{
+		{"inside chunk", [][]byte{data(8), data(8), data(8)}, []byte("XY"), 10, "=8 +8 =8"},
+		{"across chunks", [][]byte{data(8), data(8), data(8)}, []byte("WXYZ"), 6, "+8 +8 =8"},
+		{"empty write", [][]byte{data(8), data(8)}, nil, 3, "=8 =8"},
+		{"grow tail chunk", [][]byte{data(8), data(8), data(4)}, []byte("XY"), 20, "=8 =8 +6"},
+		{"overwrite tail chunk", [][]byte{data(8), data(4)}, []byte("XY"), 9, "=8 +4"},
+		{"past end", [][]byte{data(8)}, []byte("XY"), 40, "=8 h32 +2"},
+		{"past end of partial tail", [][]byte{data(8), data(4)}, []byte("XY"), 30, "=8 +8 h14 +2"},
+		{"past end spanning chunks", [][]byte{data(8)}, data(12), 16, "=8 h8 +8 +4"},
+		{"split hole", [][]byte{data(8), hole(24), data(8)}, []byte("WXYZ"), 18, "=8 h10 +4 h10 =8"},
+		{"start of hole", [][]byte{data(8), hole(24), data(8)}, []byte("XYZ"), 8, "=8 +3 h21 =8"},
+		{"end of hole", [][]byte{data(8), hole(24), data(8)}, []byte("XYZ"), 29, "=8 h21 +3 =8"},
+		{"hole into data", [][]byte{data(8), hole(8), data(8)}, data(6), 12, "=8 h4 +4 +8"},
+		{"large write into hole", [][]byte{data(8), hole(24)}, data(20), 10, "=8 h2 +8 +8 +4 h2"},
+		{"zeros become a hole", [][]byte{data(8), data(8), data(8)}, hole(8), 8, "=8 h8 =8"},
+		{"zeros into hole", [][]byte{data(8), hole(16)}, hole(4), 12, "=8 h4 h4 h8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newFakeCluster(t)
+			fc.addChunks(t, "/f", chunkSize, tt.pieces...)
+			before := make(map[string]bool)
+			var want []byte
+			for i, piece := range tt.pieces {
+				before[fmt.Sprintf("/f_%d", i)] = true
+				want = append(want, piece...)
+			}
+			if end := tt.off + int64(len(tt.p)); end > int64(len(want)) && len(tt.p) > 0 {
+				want = append(want, make([]byte, end-int64(len(want)))...)
+			}
+			copy(want[min(tt.off, int64(len(want))):], tt.p)
+
+			entry, err := fc.WriteAt(context.Background(), "/f", tt.p, tt.off)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.Size != int64(len(want)) {
+				t.Errorf("size = %d, want %d", entry.Size, len(want))
+			}
+			if got := fc.layout(t, "/f", before); got != tt.want {
+				t.Errorf("layout = %q, want %q", got, tt.want)
+			}
+			got, err := fs.ReadFile(fc.FS(context.Background()), "f")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("contents = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTruncateAndAppend(t *testing.T) {
+	const chunkSize = 8
+	data := func(n int) []byte { return bytes.Repeat([]byte("d"), n) }
+	hole := func(n int) []byte { return make([]byte, n) }
+	tests := []struct {
+		name   string
+		pieces [][]byte
+		update func(c *Client) (*Entry, error)
+		want   string
+	}{
+		{"shrink to chunk boundary", [][]byte{data(8), data(8), data(8)}, func(c *Client) (*Entry, error) {
+			return c.Truncate(context.Background(), "/f", 16)
+		}, "=8 =8"},
+		{"shrink inside chunk", [][]byte{data(8), data(8), data(8)}, func(c *Client) (*Entry, error) {
+			return c.Truncate(context.Background(), "/f", 11)
+		}, "=8 +3"},
+		{"shrink inside hole", [][]byte{data(8), hole(24)}, func(c *Client) (*Entry, error) {
+			return c.Truncate(context.Background(), "/f", 20)
+		}, "=8 h12"},
+		{"extend fills tail chunk", [][]byte{data(8), data(4)}, func(c *Client) (*Entry, error) {
+			return c.Truncate(context.Background(), "/f", 40)
+		}, "=8 +8 h24"},
+		{"append to full chunks", [][]byte{data(8), data(8)}, func(c *Client) (*Entry, error) {
+			return c.Append(context.Background(), "/f", strings.NewReader("abc"))
+		}, "=8 =8 +3"},
+		{"append rewrites tail chunk", [][]byte{data(8), data(5)}, func(c *Client) (*Entry, error) {
+			return c.Append(context.Background(), "/f", strings.NewReader("abcdef"))
+		}, "=8 +8 +3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := newFakeCluster(t)
+			fc.addChunks(t, "/f", chunkSize, tt.pieces...)
+			before := make(map[string]bool)
+			for i := range tt.pieces {
+				before[fmt.Sprintf("/f_%d", i)] = true
+			}
+			if _, err := tt.update(fc.Client); err != nil {
+				t.Fatal(err)
+			}
+			if got := fc.layout(t, "/f", before); got != tt.want {
+				t.Errorf("layout = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package dfs
+
+import (
+	"context"
+	"io/fs"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 未提交的上传
+// 上传先在元数据服务登记，分片写入后逐个登记，全部完成后一次性提交为文件
+// 提交前文件对读者不可见，超过有效期未提交的上传由元数据服务回收其分片
+type Upload struct {
+	ID        string
+	Path      string
+	ExpiresAt time.Time
+	Chunks    []metadata.FileChunk // 已登记的分片
+	Multipart bool
+	Parts     []Part // 分段上传中已提交的分段
+}
+
+func uploadFromProto(msg *metapb.UploadInfo) Upload {
+	u := Upload{ID: msg.UploadId, Path: msg.Path, ExpiresAt: time.Unix(0, msg.ExpiresAt), Multipart: msg.Multipart}
+	for _, chunk := range msg.Chunks {
+		u.Chunks = append(u.Chunks, metadata.FileChunkFromProto(chunk))
+	}
+	for _, part := range msg.Parts {
+		u.Parts = append(u.Parts, partFromProto(part))
+	}
+	return u
+}
+
+// 列出所有未提交的上传
+func (c *Client) Uploads(ctx context.Context) ([]Upload, error) {
+	resp, err := c.listUploads(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var uploads []Upload
+	for _, msg := range resp.Uploads {
+		uploads = append(uploads, uploadFromProto(msg))
+	}
+	return uploads, nil
+}
+
+// 向元数据服务列出未提交的上传和追加租约的分片
+func (c *Client) listUploads(ctx context.Context) (*metapb.ListUploadsResponse, error) {
+	var resp *metapb.ListUploadsResponse
+	err := c.callMeta(ctx, true, func(ctx context.Context) (err error) {
+		resp, err = c.meta.ListUploads(ctx, &metapb.Empty{})
+		return err
+	})
+	if err != nil {
+		return nil, pathError("uploads", "/", err)
+	}
+	return resp, nil
+}
+
+// 登记上传到 path，id 为文件标识符；续传时使用同一 id 为已有的上传续期
+// base 非 nil 时更新该文件：保留其前 keep 个分片，文件在此期间被修改则提交时返回 ErrConflict
+func (c *Client) beginUpload(ctx context.Context, path, id string, opts putOptions, base *Entry, keep int) error {
+	req := &metapb.BeginUploadRequest{
+		Path:        path,
+		UploadId:    id,
+		ChunkSize:   opts.chunkSize,
+		Replicas:    int32(opts.replicas),
+		WriteQuorum: int32(opts.writeQuorum),
+		Overwrite:   opts.replace || base != nil,
+		Keep:        int32(keep),
+		Multipart:   opts.multipart,
+	}
+	if base != nil && !base.ModificationTime.IsZero() {
+		req.BaseModificationTime = base.ModificationTime.UnixNano()
+	}
+	err := c.callMeta(ctx, true, func(ctx context.Context) error {
+		_, err := c.meta.BeginUpload(ctx, req)
+		return err
+	})
+	return pathError("put", path, err)
+}
+
+// 登记已写入的分片
+func (c *Client) addChunk(ctx context.Context, path, id string, chunk metadata.FileChunk) error {
+	err := c.callMeta(ctx, true, func(ctx context.Context) error {
+		_, err := c.meta.AddChunk(ctx, &metapb.AddChunkRequest{UploadId: id, Chunk: chunk.ToProto()})
+		return err
+	})
+	return uploadError("put", path, err)
+}
+
+// 提交上传，返回提交后的文件元数据
+// written 为本次写入的分片数，更新文件中间的分片时，其后的分片沿用原文件
+func (c *Client) commitUpload(ctx context.Context, path, id string, size int64, chunks, written int) (*Entry, error) {
+	var resp *metapb.FileMetadata
+	err := c.callMeta(ctx, false, func(ctx context.Context) (err error) {
+		resp, err = c.meta.CommitUpload(ctx, &metapb.CommitUploadRequest{UploadId: id, Size: size, Chunks: int32(chunks), Written: int32(written)})
+		return err
+	})
+	if err != nil {
+		return nil, uploadError("put", path, err)
+	}
+	return &Entry{Path: path, FileMetadata: metadata.FileMetadataFromProto(resp)}, nil
+}
+
+// 放弃上传，元数据服务回收已登记的分片
+func (c *Client) abortUpload(ctx context.Context, path, id string) error {
+	err := c.callMeta(ctx, false, func(ctx context.Context) error {
+		_, err := c.meta.AbortUpload(ctx, &metapb.UploadRequest{UploadId: id})
+		return err
+	})
+	return uploadError("abort", path, err)
+}
+
+// 上传不存在时返回 ErrUploadExpired，其余错误同 pathError
+func uploadError(op, path string, err error) error {
+	if status.Code(err) == codes.NotFound {
+		return &fs.PathError{Op: op, Path: path, Err: ErrUploadExpired}
+	}
+	return pathError(op, path, err)
+}
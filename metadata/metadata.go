@@ -1,19 +1,99 @@
 package metadata
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"slices"
+	"strings"
 	"time"
 )
 
+// 目录树操作返回的错误
+var (
+	ErrFileNotFound = errors.New("file not found")
+	ErrDirNotFound  = errors.New("directory not found")
+	ErrFileExists   = errors.New("file already exists")
+	ErrDirExists    = errors.New("directory already exists")
+	ErrNotDirectory = errors.New("not a directory")
+	ErrNotEmpty     = errors.New("directory not empty")
+	ErrIsDirectory  = errors.New("is a directory")
+	ErrInvalidName  = errors.New("invalid name")
+)
+
+// 请求的 gRPC 元数据中路径租约持有者的键
+const LockOwnerKey = "dfs-lock-owner"
+
 type FileChunk struct {
-	ChunkID         string   // 分片唯一标识符（如 UUID）
+	ChunkID         string   // 分片唯一标识符（如 UUID），为空表示空洞
 	FileID          string   // 所属文件唯一标识符
 	ChunkNumber     int      // 分片编号，从 0 开始
 	OriginalName    string   // 原始文件名
 	Size            int64    // 分片大小（字节）
 	Checksum        string   // 分片校验值，用于数据完整性校验
-	StorageLocation int      // 分片存储位置（路径、URI 等）
-	Replicas        []string // 副本存储位置列表
+	StorageLocation string   // 主副本所在存储节点 ID
+	Replicas        []string // 其余副本所在存储节点 ID 列表
+	Pending         []string // 尚未写入、等待后台修复的副本所在存储节点 ID 列表
+}
+
+// 空洞：没有写入存储节点的全零分片，读取时返回 Size 个零字节
+func (c *FileChunk) IsHole() bool {
+	return c.ChunkID == ""
+}
+
+// 分片所有副本所在的存储节点 ID，主副本在前
+func (c *FileChunk) Locations() []string {
+	if c.StorageLocation == "" {
+		return append([]string(nil), c.Replicas...)
+	}
+	return append([]string{c.StorageLocation}, c.Replicas...)
+}
+
+// 记录新的副本位置，该节点不再等待修复，已存在时返回 false
+func (c *FileChunk) AddLocation(nodeID string) bool {
+	c.Pending = slices.DeleteFunc(c.Pending, func(id string) bool { return id == nodeID })
+	for _, id := range c.Locations() {
+		if id == nodeID {
+			return false
+		}
+	}
+	if c.StorageLocation == "" {
+		c.StorageLocation = nodeID
+	} else {
+		c.Replicas = append(c.Replicas, nodeID)
+	}
+	return true
+}
+
+// 移除副本位置，主副本被移除时由第一个其余副本接替，不存在时返回 false
+func (c *FileChunk) RemoveLocation(nodeID string) bool {
+	locations := c.Locations()
+	for i, id := range locations {
+		if id == nodeID {
+			locations = append(locations[:i], locations[i+1:]...)
+			c.StorageLocation, c.Replicas = "", nil
+			if len(locations) > 0 {
+				c.StorageLocation, c.Replicas = locations[0], locations[1:]
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// 记录该节点上的副本需要修复，已记录或已持有副本时返回 false
+func (c *FileChunk) AddPending(nodeID string) bool {
+	if slices.Contains(c.Pending, nodeID) || slices.Contains(c.Locations(), nodeID) {
+		return false
+	}
+	c.Pending = append(c.Pending, nodeID)
+	return true
+}
+
+// 计算分片校验值（SHA-256）
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 // 文件元数据
@@ -24,6 +104,27 @@ type FileMetadata struct {
 	CreationTime     time.Time
 	ModificationTime time.Time
 	Chunks           []FileChunk // 分片信息列表
+	ChunkSize        int64       // 分片大小（字节）；目录中为新文件的默认值，0 表示沿用上级目录
+	Replicas         int         // 每个分片的副本数；目录中为新文件的默认值，0 表示沿用上级目录
+	WriteQuorum      int         // 写入成功所需的副本确认数；目录中为新文件的默认值，0 表示沿用上级目录
+}
+
+// 实际存储的字节数，不含空洞（也不计副本数）
+func (m *FileMetadata) AllocatedSize() int64 {
+	var size int64
+	for _, chunk := range m.Chunks {
+		if !chunk.IsHole() {
+			size += chunk.Size
+		}
+	}
+	return size
+}
+
+// 目录下新文件的默认值，0 表示未设置
+type Defaults struct {
+	ChunkSize   int64
+	Replicas    int
+	WriteQuorum int
 }
 
 // 文件树节点
@@ -54,22 +155,34 @@ func NewFileTree() *FileTree {
 
 // 创建目录
 func (t *FileTree) Mkdir(name string) error {
-	if _, exists := t.Current.Children[name]; exists {
-		return errors.New("directory already exists")
+	return t.Current.mkdir(name)
+}
+
+func (n *FileNode) mkdir(name string) error {
+	if !validName(name) {
+		return ErrInvalidName
+	}
+	if _, exists := n.Children[name]; exists {
+		return ErrDirExists
 	}
-	node := &FileNode{
+	n.Children[name] = &FileNode{
 		Metadata: &FileMetadata{
 			Name:         name,
 			IsDirectory:  true,
 			CreationTime: time.Now(),
 		},
 		Children: make(map[string]*FileNode),
-		Parent:   t.Current,
+		Parent:   n,
 	}
-	t.Current.Children[name] = node
 	return nil
 }
 
+// 目录项名称不能为空、"." 或 ".."，也不能包含 "/"
+// SplitPath("/") 返回的名称为 "/"，据此拒绝以根目录为目标的创建
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
 // 切换目录
 func (t *FileTree) Cd(name string) error {
 	if name == ".." {
@@ -83,7 +196,7 @@ func (t *FileTree) Cd(name string) error {
 		t.Current = node
 		return nil
 	}
-	return errors.New("directory not found")
+	return ErrDirNotFound
 }
 
 // 列出目录
@@ -97,13 +210,20 @@ func (t *FileTree) Ls() []string {
 
 // 添加文件
 func (t *FileTree) AddFile(metadata *FileMetadata) error {
+	return t.Current.addFile(metadata)
+}
+
+func (n *FileNode) addFile(metadata *FileMetadata) error {
 	name := metadata.Name
-	if _, exists := t.Current.Children[name]; exists {
-		return errors.New("file already exists")
+	if !validName(name) {
+		return ErrInvalidName
+	}
+	if _, exists := n.Children[name]; exists {
+		return ErrFileExists
 	}
-	t.Current.Children[name] = &FileNode{
+	n.Children[name] = &FileNode{
 		Metadata: metadata,
-		Parent:   t.Current,
+		Parent:   n,
 	}
 	return nil
 }
@@ -111,7 +231,7 @@ func (t *FileTree) AddFile(metadata *FileMetadata) error {
 // 删除文件
 func (t *FileTree) RemoveFile(name string) error {
 	if _, exists := t.Current.Children[name]; !exists {
-		return errors.New("file not found")
+		return ErrFileNotFound
 	}
 	delete(t.Current.Children, name)
 	return nil
@@ -121,7 +241,19 @@ func (t *FileTree) RemoveFile(name string) error {
 func (t *FileTree) GetFileMetadata(name string) (*FileMetadata, error) {
 	node, exists := t.Current.Children[name]
 	if !exists {
-		return nil, errors.New("file not found")
+		return nil, ErrFileNotFound
 	}
 	return node.Metadata, nil
 }
+
+// 遍历文件树中的所有节点，path 为完整路径
+func (t *FileTree) Walk(fn func(path string, metadata *FileMetadata)) {
+	walk(t.Root, "/", fn)
+}
+
+func walk(node *FileNode, path string, fn func(path string, metadata *FileMetadata)) {
+	fn(path, node.Metadata)
+	for name, child := range node.Children {
+		walk(child, strings.TrimSuffix(path, "/")+"/"+name, fn)
+	}
+}
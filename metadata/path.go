@@ -0,0 +1,179 @@
+package metadata
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// 规范化绝对路径，如 "a//b/" -> "/a/b"
+func CleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// 拆分为父目录和文件名
+func SplitPath(p string) (dir, name string) {
+	p = CleanPath(p)
+	return path.Dir(p), path.Base(p)
+}
+
+// 按绝对路径查找节点
+func (t *FileTree) Lookup(p string) (*FileNode, error) {
+	node := t.Root
+	for _, name := range strings.Split(strings.Trim(CleanPath(p), "/"), "/") {
+		if name == "" {
+			continue
+		}
+		child, exists := node.Children[name]
+		if !exists {
+			return nil, ErrFileNotFound
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// 查找目录节点
+func (t *FileTree) lookupDir(p string) (*FileNode, error) {
+	node, err := t.Lookup(p)
+	if err != nil {
+		return nil, ErrDirNotFound
+	}
+	if !node.Metadata.IsDirectory {
+		return nil, ErrNotDirectory
+	}
+	return node, nil
+}
+
+// 按绝对路径创建目录
+func (t *FileTree) MkdirAt(p string) error {
+	dir, name := SplitPath(p)
+	parent, err := t.lookupDir(dir)
+	if err != nil {
+		return err
+	}
+	return parent.mkdir(name)
+}
+
+// 在 dir 目录下添加文件
+func (t *FileTree) AddFileAt(dir string, metadata *FileMetadata) error {
+	parent, err := t.lookupDir(dir)
+	if err != nil {
+		return err
+	}
+	return parent.addFile(metadata)
+}
+
+// 按绝对路径删除文件或空目录
+func (t *FileTree) RemoveAt(p string) error {
+	node, err := t.Lookup(p)
+	if err != nil {
+		return err
+	}
+	if node == t.Root {
+		return errors.New("cannot remove root")
+	}
+	if len(node.Children) > 0 {
+		return ErrNotEmpty
+	}
+	if t.Current == node {
+		t.Current = node.Parent
+	}
+	delete(node.Parent.Children, node.Metadata.Name)
+	return nil
+}
+
+// 列出目录下的所有条目
+func (t *FileTree) ListAt(p string) ([]*FileMetadata, error) {
+	node, err := t.lookupDir(p)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*FileMetadata
+	for _, child := range node.Children {
+		entries = append(entries, child.Metadata)
+	}
+	return entries, nil
+}
+
+// 遍历 p 及其下的所有节点，path 为完整路径
+func (t *FileTree) WalkAt(p string, fn func(path string, metadata *FileMetadata)) error {
+	node, err := t.Lookup(p)
+	if err != nil {
+		return err
+	}
+	walk(node, CleanPath(p), fn)
+	return nil
+}
+
+// 重命名或移动文件、目录；目标是已存在的目录时移动到该目录下
+func (t *FileTree) RenameAt(from, to string) error {
+	node, err := t.Lookup(from)
+	if err != nil {
+		return err
+	}
+	if node == t.Root {
+		return errors.New("cannot rename root")
+	}
+	if target, err := t.Lookup(to); err == nil && target.Metadata.IsDirectory {
+		to = path.Join(to, node.Metadata.Name)
+	}
+	dir, name := SplitPath(to)
+	if !validName(name) {
+		return ErrInvalidName
+	}
+	parent, err := t.lookupDir(dir)
+	if err != nil {
+		return err
+	}
+	if _, exists := parent.Children[name]; exists {
+		return ErrFileExists
+	}
+	for p := parent; p != nil; p = p.Parent {
+		if p == node {
+			return errors.New("cannot move a directory into itself")
+		}
+	}
+
+	delete(node.Parent.Children, node.Metadata.Name)
+	node.Metadata.Name = name
+	node.Parent = parent
+	parent.Children[name] = node
+	return nil
+}
+
+// 目录 p 下新文件的默认值，未设置的值沿上级目录查找
+func (t *FileTree) DefaultsAt(p string) (Defaults, error) {
+	node, err := t.lookupDir(p)
+	if err != nil {
+		return Defaults{}, err
+	}
+	var defaults Defaults
+	for ; node != nil; node = node.Parent {
+		if defaults.ChunkSize == 0 {
+			defaults.ChunkSize = node.Metadata.ChunkSize
+		}
+		if defaults.Replicas == 0 {
+			defaults.Replicas = node.Metadata.Replicas
+		}
+		if defaults.WriteQuorum == 0 {
+			defaults.WriteQuorum = node.Metadata.WriteQuorum
+		}
+	}
+	return defaults, nil
+}
+
+// 设置目录 p 下新文件的默认值，0 表示沿用上级目录
+func (t *FileTree) SetDefaultsAt(p string, defaults Defaults) error {
+	if defaults.ChunkSize < 0 || defaults.Replicas < 0 || defaults.WriteQuorum < 0 {
+		return errors.New("invalid defaults")
+	}
+	node, err := t.lookupDir(p)
+	if err != nil {
+		return err
+	}
+	node.Metadata.ChunkSize = defaults.ChunkSize
+	node.Metadata.Replicas = defaults.Replicas
+	node.Metadata.WriteQuorum = defaults.WriteQuorum
+	return nil
+}
@@ -0,0 +1,51 @@
+package metadata
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMkdirAtNames(t *testing.T) {
+	tests := []struct {
+		path string
+		err  error
+	}{
+		{"/a", nil},
+		{"/a/b", nil},
+		{"a//c/", nil},
+		{"/", ErrInvalidName},
+		{"", ErrInvalidName},
+		{"/a", ErrDirExists},
+		{"/missing/x", ErrDirNotFound},
+	}
+	tree := NewFileTree()
+	for _, tt := range tests {
+		if err := tree.MkdirAt(tt.path); !errors.Is(err, tt.err) {
+			t.Errorf("MkdirAt(%q) = %v, want %v", tt.path, err, tt.err)
+		}
+	}
+	if _, exists := tree.Root.Children["/"]; exists {
+		t.Error("root has a child named \"/\"")
+	}
+}
+
+func TestAddFileAtNames(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"file", nil},
+		{"", ErrInvalidName},
+		{"/", ErrInvalidName},
+		{".", ErrInvalidName},
+		{"..", ErrInvalidName},
+		{"a/b", ErrInvalidName},
+		{"file", ErrFileExists},
+	}
+	tree := NewFileTree()
+	for _, tt := range tests {
+		if err := tree.AddFileAt("/", &FileMetadata{Name: tt.name}); !errors.Is(err, tt.err) {
+			t.Errorf("AddFileAt(%q) = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
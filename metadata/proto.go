@@ -0,0 +1,85 @@
+package metadata
+
+import (
+	"time"
+
+	metapb "grpc-distributed-fs/proto/meta"
+)
+
+// 转换为 protobuf 消息
+func (m *FileMetadata) ToProto() *metapb.FileMetadata {
+	msg := &metapb.FileMetadata{
+		Name:             m.Name,
+		IsDirectory:      m.IsDirectory,
+		Size:             m.Size,
+		CreationTime:     unixNano(m.CreationTime),
+		ModificationTime: unixNano(m.ModificationTime),
+		ChunkSize:        m.ChunkSize,
+		Replicas:         int32(m.Replicas),
+		WriteQuorum:      int32(m.WriteQuorum),
+	}
+	for i := range m.Chunks {
+		msg.Chunks = append(msg.Chunks, m.Chunks[i].ToProto())
+	}
+	return msg
+}
+
+func (c *FileChunk) ToProto() *metapb.FileChunk {
+	return &metapb.FileChunk{
+		ChunkId:         c.ChunkID,
+		FileId:          c.FileID,
+		ChunkNumber:     int64(c.ChunkNumber),
+		OriginalName:    c.OriginalName,
+		Size:            c.Size,
+		Checksum:        c.Checksum,
+		StorageLocation: c.StorageLocation,
+		Replicas:        c.Replicas,
+		Pending:         c.Pending,
+	}
+}
+
+// 从 protobuf 消息转换
+func FileMetadataFromProto(msg *metapb.FileMetadata) *FileMetadata {
+	m := &FileMetadata{
+		Name:             msg.Name,
+		IsDirectory:      msg.IsDirectory,
+		Size:             msg.Size,
+		CreationTime:     fromUnixNano(msg.CreationTime),
+		ModificationTime: fromUnixNano(msg.ModificationTime),
+		ChunkSize:        msg.ChunkSize,
+		Replicas:         int(msg.Replicas),
+		WriteQuorum:      int(msg.WriteQuorum),
+	}
+	for _, chunk := range msg.Chunks {
+		m.Chunks = append(m.Chunks, FileChunkFromProto(chunk))
+	}
+	return m
+}
+
+func FileChunkFromProto(msg *metapb.FileChunk) FileChunk {
+	return FileChunk{
+		ChunkID:         msg.ChunkId,
+		FileID:          msg.FileId,
+		ChunkNumber:     int(msg.ChunkNumber),
+		OriginalName:    msg.OriginalName,
+		Size:            msg.Size,
+		Checksum:        msg.Checksum,
+		StorageLocation: msg.StorageLocation,
+		Replicas:        msg.Replicas,
+		Pending:         msg.Pending,
+	}
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+)
+
+// 处理存储节点的块报告：以节点实际持有的分片为准更新副本位置，
+// 记录在该节点上丢失的副本，并将其标记为待修复
+func (s *metaServer) BlockReport(ctx context.Context, req *metapb.BlockReportRequest) (*metapb.BlockReportResponse, error) {
+	reported := make(map[string]bool, len(req.ChunkIds))
+	for _, chunkID := range req.ChunkIds {
+		reported[chunkID] = true
+	}
+	generatedAt := time.Unix(0, req.GeneratedAt)
+	if req.Addr != "" {
+		s.nodes.update(req.NodeId, req.Addr)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &metapb.BlockReportResponse{}
+	known := make(map[string]bool)
+	s.tree.Walk(func(path string, meta *metadata.FileMetadata) {
+		for i := range meta.Chunks {
+			chunk := &meta.Chunks[i]
+			known[chunk.ChunkID] = true
+			if reported[chunk.ChunkID] {
+				if chunk.AddLocation(req.NodeId) {
+					resp.Added++
+				}
+				delete(s.missing[chunk.ChunkID], req.NodeId)
+				delete(s.repaired[chunk.ChunkID], req.NodeId)
+				continue
+			}
+			// 报告生成之后才写入的文件或修复的副本可能还不在清单中，留到下一次报告处理
+			// 修复不改变文件的修改时间，因此按副本记录修复的时间
+			if meta.ModificationTime.After(generatedAt) || s.repaired[chunk.ChunkID][req.NodeId].After(generatedAt) {
+				continue
+			}
+			delete(s.repaired[chunk.ChunkID], req.NodeId)
+			if chunk.RemoveLocation(req.NodeId) {
+				resp.Removed++
+				chunk.AddPending(req.NodeId)
+				if s.missing[chunk.ChunkID] == nil {
+					s.missing[chunk.ChunkID] = make(map[string]bool)
+				}
+				s.missing[chunk.ChunkID][req.NodeId] = true
+			}
+		}
+	})
+	for chunkID := range reported {
+		if !known[chunkID] {
+			resp.Unknown++
+		}
+	}
+
+	log.Printf("Block report from node %s: %d chunks, %d added, %d removed, %d unknown",
+		req.NodeId, len(req.ChunkIds), resp.Added, resp.Removed, resp.Unknown)
+	// 副本位置以节点报告为准，持久化失败时保留内存中的结果，下次报告时再次持久化
+	if resp.Added > 0 || resp.Removed > 0 {
+		if err := s.persist(); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// 列出有副本丢失的分片
+func (s *metaServer) MissingReplicas(ctx context.Context, req *metapb.Empty) (*metapb.MissingReplicasResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &metapb.MissingReplicasResponse{}
+	live := make(map[string]bool)
+	s.tree.Walk(func(path string, meta *metadata.FileMetadata) {
+		for i := range meta.Chunks {
+			chunk := &meta.Chunks[i]
+			live[chunk.ChunkID] = true
+			if len(s.missing[chunk.ChunkID]) == 0 {
+				continue
+			}
+			var lost []string
+			for nodeID := range s.missing[chunk.ChunkID] {
+				lost = append(lost, nodeID)
+			}
+			sort.Strings(lost)
+			resp.Replicas = append(resp.Replicas, &metapb.MissingReplica{Path: path, Chunk: chunk.ToProto(), Lost: lost})
+		}
+	})
+
+	// 清理已删除文件的记录
+	for chunkID, lost := range s.missing {
+		if !live[chunkID] || len(lost) == 0 {
+			delete(s.missing, chunkID)
+		}
+	}
+	for chunkID, nodes := range s.repaired {
+		if !live[chunkID] || len(nodes) == 0 {
+			delete(s.repaired, chunkID)
+		}
+	}
+	return resp, nil
+}
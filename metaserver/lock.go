@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 客户端未指定有效期时路径租约的有效期
+const defaultLockTTL = 30 * time.Second
+
+// 一个客户端在路径上持有的租约
+// 租约只保存在内存中，元数据服务重启后全部失效，持有者续期时重新获取
+type lockHolder struct {
+	Exclusive bool
+	ExpiresAt time.Time
+}
+
+// 请求方的租约持有者名称，未指定时为空
+func lockOwner(ctx context.Context) string {
+	if md, ok := grpcmd.FromIncomingContext(ctx); ok {
+		if owners := md.Get(metadata.LockOwnerKey); len(owners) > 0 {
+			return owners[0]
+		}
+	}
+	return ""
+}
+
+// 获取、续期或转换路径租约，路径不必已存在
+func (s *metaServer) Lock(ctx context.Context, req *metapb.LockRequest) (*metapb.LockInfo, error) {
+	owner := lockOwner(ctx)
+	if owner == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing lock owner")
+	}
+	path := metadata.CleanPath(req.Path)
+	ttl := time.Duration(req.Ttl)
+	if ttl <= 0 {
+		ttl = defaultLockTTL
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	holders := s.lockHolders(path)
+	var others []string
+	for other, holder := range holders {
+		if other != owner && (req.Exclusive || holder.Exclusive) {
+			others = append(others, other)
+		}
+	}
+	if len(others) > 0 {
+		return nil, lockedError(others)
+	}
+	if _, renew := holders[owner]; !renew {
+		log.Printf("Granted %s lock on %s to %s", lockMode(req.Exclusive), path, owner)
+	}
+	if holders == nil {
+		holders = make(map[string]*lockHolder)
+		s.locks[path] = holders
+	}
+	holders[owner] = &lockHolder{Exclusive: req.Exclusive, ExpiresAt: time.Now().Add(ttl)}
+	return lockInfo(path, holders), nil
+}
+
+// 释放请求方在路径上的租约，未持有时忽略
+func (s *metaServer) Unlock(ctx context.Context, req *metapb.PathRequest) (*metapb.Empty, error) {
+	owner := lockOwner(ctx)
+	path := metadata.CleanPath(req.Path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if holders := s.lockHolders(path); holders[owner] != nil {
+		delete(holders, owner)
+		if len(holders) == 0 {
+			delete(s.locks, path)
+		}
+		log.Printf("Released lock on %s held by %s", path, owner)
+	}
+	return &metapb.Empty{}, nil
+}
+
+// 路径上仍然有效的租约，顺带清理已过期的租约，调用方需持有锁
+func (s *metaServer) lockHolders(path string) map[string]*lockHolder {
+	holders := s.locks[path]
+	now := time.Now()
+	for owner, holder := range holders {
+		if now.After(holder.ExpiresAt) {
+			log.Printf("Lock on %s held by %s expired", path, owner)
+			delete(holders, owner)
+		}
+	}
+	if len(holders) == 0 {
+		delete(s.locks, path)
+		return nil
+	}
+	return holders
+}
+
+// 检查请求方能否修改 paths：其他客户端持有其中任一路径的租约（无论共享还是排他）时返回 FailedPrecondition
+// 调用方需持有锁
+func (s *metaServer) checkLocks(ctx context.Context, paths ...string) error {
+	owner := lockOwner(ctx)
+	for _, path := range paths {
+		var others []string
+		for other := range s.lockHolders(metadata.CleanPath(path)) {
+			if other != owner {
+				others = append(others, other)
+			}
+		}
+		if len(others) > 0 {
+			return lockedError(others)
+		}
+	}
+	return nil
+}
+
+// 同 checkLocks，但还检查 roots 下所有路径上的租约，用于重命名和删除目录，被租约的路径不必已存在
+// 调用方需持有锁
+func (s *metaServer) checkTreeLocks(ctx context.Context, roots ...string) error {
+	var paths []string
+	for _, root := range roots {
+		root = metadata.CleanPath(root)
+		prefix := strings.TrimSuffix(root, "/") + "/"
+		var under []string
+		for path := range s.locks {
+			if strings.HasPrefix(path, prefix) {
+				under = append(under, path)
+			}
+		}
+		sort.Strings(under)
+		paths = append(append(paths, root), under...)
+	}
+	return s.checkLocks(ctx, paths...)
+}
+
+// 客户端按 "locked by " 前缀识别租约冲突
+func lockedError(owners []string) error {
+	sort.Strings(owners)
+	return status.Errorf(codes.FailedPrecondition, "locked by %s", strings.Join(owners, ", "))
+}
+
+func lockInfo(path string, holders map[string]*lockHolder) *metapb.LockInfo {
+	info := &metapb.LockInfo{Path: path}
+	for owner, holder := range holders {
+		info.Holders = append(info.Holders, &metapb.LockHolder{Owner: owner, Exclusive: holder.Exclusive, ExpiresAt: holder.ExpiresAt.UnixNano()})
+	}
+	sort.Slice(info.Holders, func(i, j int) bool { return info.Holders[i].Owner < info.Holders[j].Owner })
+	return info
+}
+
+func lockMode(exclusive bool) string {
+	if exclusive {
+		return "exclusive"
+	}
+	return "shared"
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 以 owner 为租约持有者的请求上下文
+func ownerContext(owner string) context.Context {
+	return grpcmd.NewIncomingContext(context.Background(), grpcmd.Pairs(metadata.LockOwnerKey, owner))
+}
+
+func TestLockConflicts(t *testing.T) {
+	type step struct {
+		owner     string
+		unlock    bool // 释放而不是获取租约
+		exclusive bool
+		ttl       time.Duration
+		wantErr   string // 冲突时错误信息中的持有者，空表示成功
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"shared with shared", []step{
+			{owner: "alice"},
+			{owner: "bob"},
+		}},
+		{"exclusive after shared", []step{
+			{owner: "alice"},
+			{owner: "bob", exclusive: true, wantErr: "locked by alice"},
+		}},
+		{"shared after exclusive", []step{
+			{owner: "alice", exclusive: true},
+			{owner: "bob", wantErr: "locked by alice"},
+		}},
+		{"conflict lists every holder", []step{
+			{owner: "bob"},
+			{owner: "alice"},
+			{owner: "carol", exclusive: true, wantErr: "locked by alice, bob"},
+		}},
+		{"renew and upgrade alone", []step{
+			{owner: "alice"},
+			{owner: "alice"},
+			{owner: "alice", exclusive: true},
+			{owner: "alice"},
+		}},
+		{"upgrade blocked by another reader", []step{
+			{owner: "alice"},
+			{owner: "bob"},
+			{owner: "alice", exclusive: true, wantErr: "locked by bob"},
+		}},
+		{"released", []step{
+			{owner: "alice", exclusive: true},
+			{owner: "alice", unlock: true},
+			{owner: "bob", exclusive: true},
+		}},
+		{"unlock by another owner is ignored", []step{
+			{owner: "alice", exclusive: true},
+			{owner: "bob", unlock: true},
+			{owner: "bob", wantErr: "locked by alice"},
+		}},
+		{"expired", []step{
+			{owner: "alice", exclusive: true, ttl: time.Nanosecond},
+			{owner: "bob", exclusive: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			for i, st := range tt.steps {
+				ctx := ownerContext(st.owner)
+				var err error
+				if st.unlock {
+					_, err = s.Unlock(ctx, &metapb.PathRequest{Path: "/f"})
+				} else {
+					_, err = s.Lock(ctx, &metapb.LockRequest{Path: "/f", Exclusive: st.exclusive, Ttl: int64(st.ttl)})
+				}
+				switch {
+				case st.wantErr == "" && err != nil:
+					t.Fatalf("step %d: %v", i, err)
+				case st.wantErr != "" && (status.Code(err) != codes.FailedPrecondition || !strings.Contains(err.Error(), st.wantErr)):
+					t.Fatalf("step %d: %v, want FailedPrecondition %q", i, err, st.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestLockMissingOwner(t *testing.T) {
+	s := newTestServer(t)
+	_, err := s.Lock(context.Background(), &metapb.LockRequest{Path: "/f"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Lock = %v, want InvalidArgument", err)
+	}
+}
+
+// alice 持有共享租约时，其他客户端不能修改被租约的路径、其上级目录，也不能移动到该路径，alice 自己可以
+func TestLeaseBlocksChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		locked string
+		call   func(ctx context.Context, s *metaServer) error
+	}{
+		{"remove file", "/d/x", func(ctx context.Context, s *metaServer) error {
+			_, err := s.RemoveFile(ctx, &metapb.PathRequest{Path: "/d/x"})
+			return err
+		}},
+		{"remove parent", "/e/missing", func(ctx context.Context, s *metaServer) error {
+			_, err := s.RemoveFile(ctx, &metapb.PathRequest{Path: "/e"})
+			return err
+		}},
+		{"rename file", "/d/x", func(ctx context.Context, s *metaServer) error {
+			_, err := s.Rename(ctx, &metapb.RenameRequest{From: "/d/x", To: "/y"})
+			return err
+		}},
+		{"rename parent", "/d/x", func(ctx context.Context, s *metaServer) error {
+			_, err := s.Rename(ctx, &metapb.RenameRequest{From: "/d", To: "/moved"})
+			return err
+		}},
+		{"rename onto locked path", "/y", func(ctx context.Context, s *metaServer) error {
+			_, err := s.Rename(ctx, &metapb.RenameRequest{From: "/d/x", To: "/y"})
+			return err
+		}},
+		{"rename into directory onto locked path", "/e/x", func(ctx context.Context, s *metaServer) error {
+			_, err := s.Rename(ctx, &metapb.RenameRequest{From: "/d/x", To: "/e"})
+			return err
+		}},
+		{"overwrite", "/d/x", func(ctx context.Context, s *metaServer) error {
+			_, err := s.BeginUpload(ctx, &metapb.BeginUploadRequest{Path: "/d/x", UploadId: "u", Overwrite: true})
+			return err
+		}},
+		{"append", "/d/x", func(ctx context.Context, s *metaServer) error {
+			_, err := s.GrantAppendLease(ctx, &metapb.AppendLeaseRequest{Path: "/d/x", Proposed: &metapb.FileChunk{ChunkId: "c", StorageLocation: "node1"}, ChunkSize: 100})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		for _, owner := range []string{"bob", "alice"} {
+			t.Run(tt.name+" by "+owner, func(t *testing.T) {
+				s := newTestServer(t)
+				s.tree.MkdirAt("/d")
+				s.tree.MkdirAt("/e")
+				addTestFile(t, s, "/d/x", testChunks("x", 1, 10))
+				if _, err := s.Lock(ownerContext("alice"), &metapb.LockRequest{Path: tt.locked}); err != nil {
+					t.Fatal(err)
+				}
+				err := tt.call(ownerContext(owner), s)
+				if owner == "alice" {
+					if err != nil {
+						t.Fatalf("lease holder blocked: %v", err)
+					}
+					return
+				}
+				if status.Code(err) != codes.FailedPrecondition || !strings.Contains(err.Error(), "locked by alice") {
+					t.Fatalf("got %v, want FailedPrecondition locked by alice", err)
+				}
+				if _, err := s.tree.Lookup("/d/x"); err != nil {
+					t.Errorf("/d/x changed: %v", err)
+				}
+			})
+		}
+	}
+}
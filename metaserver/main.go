@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"slices"
+	"time"
+
+	"grpc-distributed-fs/dfs"
+	"grpc-distributed-fs/placement"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc"
+)
+
+func main() {
+	port := flag.String("port", "50050", "listen port")
+	snapshot := flag.String("db", "meta.json", "metadata snapshot file")
+	repairInterval := flag.Duration("repair-interval", 30*time.Second, "interval between pending replica repairs")
+	uploadTTL := flag.Duration("upload-timeout", 10*time.Minute, "uncommitted uploads expire after this long without progress")
+	multipartTTL := flag.Duration("multipart-timeout", 24*time.Hour, "multipart uploads expire after this long without progress")
+	leaseDuration := flag.Duration("lease-duration", time.Minute, "duration of record append leases")
+	clusterConfig := flag.String("cluster", "", "storage node config file (JSON), for node weights when re-placing replicas")
+	domainLevel := flag.String("domain", dfs.DefaultDomainLevel, "failure domain level re-placed replicas must span: zone, rack or host")
+	nodeTimeout := flag.Duration("node-timeout", 5*time.Minute, "storage nodes without a block report for this long have left the cluster (0 disables)")
+	flag.Parse()
+	if !slices.Contains(placement.Levels, *domainLevel) {
+		log.Fatalf("Unknown failure domain level %q", *domainLevel)
+	}
+	if *repairInterval <= 0 || *uploadTTL <= 0 || *multipartTTL <= 0 || *leaseDuration <= 0 {
+		log.Fatal("-repair-interval, -upload-timeout, -multipart-timeout and -lease-duration must be positive")
+	}
+	var nodes []dfs.NodeConfig
+	if *clusterConfig != "" {
+		var err error
+		nodes, err = dfs.LoadNodeConfigs(*clusterConfig)
+		if err != nil {
+			log.Fatalf("Failed to load cluster config: %v", err)
+		}
+	}
+
+	// 从快照恢复元数据
+	server, err := newMetaServer(*snapshot, *uploadTTL, *multipartTTL, *leaseDuration)
+	if err != nil {
+		log.Fatalf("Failed to load metadata: %v", err)
+	}
+
+	// 后台修复写入时未达到的副本和块报告发现丢失的副本，目标节点不可用时另选节点
+	for _, node := range nodes {
+		if node.Weight > 0 {
+			server.nodes.weights[node.ID] = node.Weight
+		}
+	}
+	server.nodes.level, server.nodes.timeout = *domainLevel, *nodeTimeout
+	server.startRepair(*repairInterval)
+	// 回收过期未提交的上传
+	server.startUploadExpiry(max(min(*uploadTTL/2, time.Minute), time.Second))
+
+	// 启动 gRPC 服务
+	lis, err := net.Listen("tcp", ":"+*port)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	metapb.RegisterMetadataServer(grpcServer, server)
+
+	log.Printf("Metadata server is running on port %s", *port)
+	if err := grpcServer.Serve(lis); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
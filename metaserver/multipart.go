@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 分段上传中一次分段写入，同一编号可以多次写入，以最后提交的一次为准
+type part struct {
+	ID          string
+	Number      int                        // 分段编号，从 1 开始
+	Chunks      map[int]metadata.FileChunk // 分段内的分片编号 -> 已写入的分片
+	Size        int64                      // 提交时的分段大小
+	Committed   bool
+	CommittedAt time.Time
+}
+
+func (p *part) toProto() *metapb.PartInfo {
+	return &metapb.PartInfo{
+		PartNumber:  int32(p.Number),
+		PartId:      p.ID,
+		Size:        p.Size,
+		Chunks:      int32(len(p.Chunks)),
+		CommittedAt: p.CommittedAt.UnixNano(),
+	}
+}
+
+// 全部分段写入（含未提交的），按编号排序
+func (u *upload) partList() []*part {
+	parts := make([]*part, 0, len(u.Parts))
+	for _, p := range u.Parts {
+		parts = append(parts, p)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		if parts[i].Number != parts[j].Number {
+			return parts[i].Number < parts[j].Number
+		}
+		return parts[i].ID < parts[j].ID
+	})
+	return parts
+}
+
+// 已提交的分段，按编号排序，每个编号至多一个
+func (u *upload) committedParts() []*part {
+	var parts []*part
+	for _, p := range u.partList() {
+		if p.Committed {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// 查找或登记分段写入 id，编号须与首次登记时一致
+func (u *upload) part(id string, number int) (*part, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing part id")
+	}
+	if number < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid part number %d", number)
+	}
+	p, exists := u.Parts[id]
+	if !exists {
+		p = &part{ID: id, Number: number, Chunks: make(map[int]metadata.FileChunk)}
+		u.Parts[id] = p
+	}
+	if p.Number != number {
+		return nil, status.Errorf(codes.InvalidArgument, "part write %s belongs to part %d", id, p.Number)
+	}
+	return p, nil
+}
+
+// 登记分段写入中的分片，已提交的分段写入不再接受分片
+func (u *upload) addPartChunk(id string, number int, chunk metadata.FileChunk) error {
+	p, err := u.part(id, number)
+	if err != nil {
+		return err
+	}
+	if p.Committed {
+		return status.Errorf(codes.FailedPrecondition, "part write %s already committed", id)
+	}
+	p.Chunks[chunk.ChunkNumber] = chunk
+	return nil
+}
+
+// 获取未提交的上传，供其他客户端继续写入分段上传
+func (s *metaServer) GetUpload(ctx context.Context, req *metapb.UploadRequest) (*metapb.UploadInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.lookupUpload(req.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	return u.toProto(), nil
+}
+
+// 提交一次分段写入：校验分片齐全后替换同一编号之前提交的写入，被替换的分片在后台回收
+// 已提交时直接返回，便于重试
+func (s *metaServer) CommitPart(ctx context.Context, req *metapb.CommitPartRequest) (*metapb.PartInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.lookupUpload(req.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Multipart {
+		return nil, status.Errorf(codes.FailedPrecondition, "upload %s is not a multipart upload", u.ID)
+	}
+	p, err := u.part(req.PartId, int(req.PartNumber))
+	if err != nil {
+		return nil, err
+	}
+	if p.Committed {
+		return p.toProto(), nil
+	}
+
+	count := int(req.Chunks)
+	if len(p.Chunks) != count {
+		return nil, status.Errorf(codes.FailedPrecondition, "part incomplete: %d of %d chunks recorded", len(p.Chunks), count)
+	}
+	var size int64
+	for number := range count {
+		chunk, exists := p.Chunks[number]
+		if !exists {
+			return nil, status.Errorf(codes.FailedPrecondition, "part incomplete: chunk %d not recorded", number)
+		}
+		size += chunk.Size
+	}
+	if size != req.Size {
+		return nil, status.Errorf(codes.FailedPrecondition, "part incomplete: %d of %d bytes recorded", size, req.Size)
+	}
+
+	var replaced []metadata.FileChunk
+	removed := make(map[string]*part)
+	for id, other := range u.Parts {
+		if other.Committed && other.Number == p.Number {
+			replaced = append(replaced, sortedChunks(other.Chunks)...)
+			removed[id] = other
+			delete(u.Parts, id)
+		}
+	}
+	p.Committed, p.Size, p.CommittedAt = true, size, time.Now()
+	s.renewUpload(u)
+	err = s.persistOrUndo(func() {
+		p.Committed, p.Size, p.CommittedAt = false, 0, time.Time{}
+		for id, other := range removed {
+			u.Parts[id] = other
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Committed part %d of upload %s: %d chunk(s), %d bytes", p.Number, u.ID, count, size)
+	if len(replaced) > 0 {
+		go s.reclaim(u.ID, replaced)
+	}
+	return p.toProto(), nil
+}
+
+// 完成分段上传：按编号拼接所选分段的分片列表并提交为文件，不复制数据
+// 未使用的分段写入和被替换的原文件的分片在后台回收
+func (s *metaServer) CompleteMultipartUpload(ctx context.Context, req *metapb.CompleteMultipartRequest) (*metapb.FileMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.lookupUpload(req.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLocks(ctx, u.Path); err != nil {
+		return nil, err
+	}
+	if !u.Multipart {
+		return nil, status.Errorf(codes.FailedPrecondition, "upload %s is not a multipart upload", u.ID)
+	}
+
+	committed := make(map[int]*part)
+	var numbers []int
+	for _, p := range u.committedParts() {
+		committed[p.Number] = p
+		numbers = append(numbers, p.Number)
+	}
+	if len(req.PartNumbers) > 0 {
+		numbers = numbers[:0]
+		for _, number := range req.PartNumbers {
+			numbers = append(numbers, int(number))
+		}
+	}
+	if len(numbers) == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "upload %s has no committed parts", u.ID)
+	}
+
+	// 分片按分段顺序重新编号
+	used := make(map[string]bool)
+	var chunks []metadata.FileChunk
+	var size int64
+	for i, number := range numbers {
+		if i > 0 && number <= numbers[i-1] {
+			return nil, status.Error(codes.InvalidArgument, "part numbers must be in ascending order")
+		}
+		p, exists := committed[number]
+		if !exists {
+			return nil, status.Errorf(codes.FailedPrecondition, "part %d not uploaded", number)
+		}
+		used[p.ID] = true
+		for _, chunk := range sortedChunks(p.Chunks) {
+			chunk.ChunkNumber = len(chunks)
+			chunks = append(chunks, chunk)
+		}
+		size += p.Size
+	}
+
+	// 被替换的文件
+	var old *metadata.FileNode
+	if u.Overwrite {
+		if node, err := s.tree.Lookup(u.Path); err == nil {
+			old = node
+		}
+	}
+	if old != nil && old.Metadata.IsDirectory {
+		return nil, toStatus(metadata.ErrIsDirectory)
+	}
+	var replaced, unused []metadata.FileChunk
+	if old != nil {
+		replaced = unreferenced(old.Metadata.Chunks, chunks)
+	}
+	for _, p := range u.partList() {
+		if !used[p.ID] {
+			unused = append(unused, sortedChunks(p.Chunks)...)
+		}
+	}
+
+	meta, err := s.installUpload(u, old, chunks, size)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Completed multipart upload %s: %s, %d part(s), %d chunk(s), %d replaced", u.ID, u.Path, len(numbers), len(chunks), len(replaced))
+	if len(replaced) > 0 {
+		go s.reclaim(u.Path, replaced)
+	}
+	if len(unused) > 0 {
+		go s.reclaim(u.ID, unused)
+	}
+	return meta.ToProto(), nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 写入并提交分段：分段写入 id 的每个分片大小为 10
+func commitTestPart(t *testing.T, s *metaServer, id string, number, chunks int) {
+	t.Helper()
+	ctx := context.Background()
+	for _, chunk := range testChunks(id, chunks, 10) {
+		req := &metapb.AddChunkRequest{UploadId: "u", PartId: id, PartNumber: int32(number), Chunk: chunk.ToProto()}
+		if _, err := s.AddChunk(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := &metapb.CommitPartRequest{UploadId: "u", PartId: id, PartNumber: int32(number), Chunks: int32(chunks), Size: int64(chunks) * 10}
+	if _, err := s.CommitPart(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompleteMultipartUpload(t *testing.T) {
+	type partWrite struct {
+		id     string
+		number int
+		chunks int
+	}
+	tests := []struct {
+		name     string
+		parts    []partWrite // 按顺序写入并提交
+		numbers  []int32
+		want     []string // 完成后文件的分片
+		reclaim  []string // 回收的分片：被替换的和未使用的分段
+		wantCode codes.Code
+	}{
+		{
+			name:  "committed out of order",
+			parts: []partWrite{{"c", 3, 1}, {"a", 1, 2}, {"b", 2, 1}},
+			want:  []string{"a_0", "a_1", "b_0", "c_0"},
+		},
+		{
+			name:  "gaps in part numbers",
+			parts: []partWrite{{"a", 1, 1}, {"b", 5, 1}, {"c", 10, 1}},
+			want:  []string{"a_0", "b_0", "c_0"},
+		},
+		{
+			name:    "selected parts",
+			parts:   []partWrite{{"a", 1, 1}, {"b", 2, 2}, {"c", 3, 1}},
+			numbers: []int32{1, 3},
+			want:    []string{"a_0", "c_0"},
+			reclaim: []string{"b_0", "b_1"},
+		},
+		{
+			name:    "part rewritten",
+			parts:   []partWrite{{"a", 1, 1}, {"b", 2, 1}, {"b2", 2, 2}},
+			want:    []string{"a_0", "b2_0", "b2_1"},
+			reclaim: []string{"b_0"},
+		},
+		{
+			name:     "descending numbers",
+			parts:    []partWrite{{"a", 1, 1}, {"b", 2, 1}},
+			numbers:  []int32{2, 1},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "duplicate numbers",
+			parts:    []partWrite{{"a", 1, 1}, {"b", 2, 1}},
+			numbers:  []int32{1, 1},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "part not uploaded",
+			parts:    []partWrite{{"a", 1, 1}},
+			numbers:  []int32{1, 2},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "no parts",
+			wantCode: codes.FailedPrecondition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			node := startDeleteRecorder(t, s)
+			beginTestUpload(t, s, &metapb.BeginUploadRequest{Path: "/f", UploadId: "u", Multipart: true}, nil)
+			for _, p := range tt.parts {
+				commitTestPart(t, s, p.id, p.number, p.chunks)
+			}
+
+			_, err := s.CompleteMultipartUpload(context.Background(), &metapb.CompleteMultipartRequest{UploadId: "u", PartNumbers: tt.numbers})
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Fatalf("CompleteMultipartUpload = %v, want %v", err, tt.wantCode)
+				}
+				if s.uploads["u"] == nil {
+					t.Error("failed completion removed the upload")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, err := s.tree.Lookup("/f")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chunkIDs(file.Metadata.Chunks); !slices.Equal(got, tt.want) {
+				t.Errorf("chunks = %v, want %v", got, tt.want)
+			}
+			for i, chunk := range file.Metadata.Chunks {
+				if chunk.ChunkNumber != i {
+					t.Errorf("chunk %s numbered %d at position %d", chunk.ChunkID, chunk.ChunkNumber, i)
+				}
+			}
+			if want := int64(len(tt.want)) * 10; file.Metadata.Size != want {
+				t.Errorf("size = %d, want %d", file.Metadata.Size, want)
+			}
+			if got := node.wait(t, len(tt.reclaim)); !slices.Equal(got, tt.reclaim) {
+				t.Errorf("reclaimed %v, want %v", got, tt.reclaim)
+			}
+		})
+	}
+}
+
+func TestCommitPart(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	beginTestUpload(t, s, &metapb.BeginUploadRequest{Path: "/f", UploadId: "u", Multipart: true}, nil)
+	add := func(id string, number, chunk int) error {
+		c := testChunks(id, chunk+1, 10)[chunk]
+		_, err := s.AddChunk(ctx, &metapb.AddChunkRequest{UploadId: "u", PartId: id, PartNumber: int32(number), Chunk: c.ToProto()})
+		return err
+	}
+	commit := func(id string, number, chunks int) error {
+		_, err := s.CommitPart(ctx, &metapb.CommitPartRequest{UploadId: "u", PartId: id, PartNumber: int32(number), Chunks: int32(chunks), Size: int64(chunks) * 10})
+		return err
+	}
+
+	steps := []struct {
+		name     string
+		call     func() error
+		wantCode codes.Code
+	}{
+		{"invalid part number", func() error { return add("a", 0, 0) }, codes.InvalidArgument},
+		{"missing part id", func() error { return add("", 1, 0) }, codes.InvalidArgument},
+		{"first chunk", func() error { return add("a", 1, 1) }, codes.OK},
+		{"commit with a chunk missing", func() error { return commit("a", 1, 2) }, codes.FailedPrecondition},
+		{"part id reused for another number", func() error { return add("a", 2, 0) }, codes.InvalidArgument},
+		{"second chunk", func() error { return add("a", 1, 0) }, codes.OK},
+		{"commit", func() error { return commit("a", 1, 2) }, codes.OK},
+		{"commit again", func() error { return commit("a", 1, 2) }, codes.OK},
+		{"add after commit", func() error { return add("a", 1, 2) }, codes.FailedPrecondition},
+	}
+	for _, st := range steps {
+		if err := st.call(); status.Code(err) != st.wantCode {
+			t.Fatalf("%s: %v, want %v", st.name, err, st.wantCode)
+		}
+	}
+}
+
+func TestPartList(t *testing.T) {
+	u := &upload{Parts: map[string]*part{
+		"z": {ID: "z", Number: 1},
+		"b": {ID: "b", Number: 3, Committed: true},
+		"a": {ID: "a", Number: 3},
+		"c": {ID: "c", Number: 2, Committed: true},
+		"y": {ID: "y", Number: 1, Committed: true},
+	}}
+	var got []string
+	for _, p := range u.partList() {
+		got = append(got, p.ID)
+	}
+	if want := []string{"y", "z", "c", "a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("partList = %v, want %v", got, want)
+	}
+	got = got[:0]
+	for _, p := range u.committedParts() {
+		got = append(got, p.ID)
+	}
+	if want := []string{"y", "c", "b"}; !slices.Equal(got, want) {
+		t.Errorf("committedParts = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 文件的追加租约：同一时间只有一个分片接受记录追加，由其主副本决定记录的偏移
+// 租约只保存在内存中，元数据服务重启后客户端重新申请，新的追加写入新分片
+type appendLease struct {
+	Chunk     metadata.FileChunk // 可追加的分片，首次提交前不在文件的分片列表中
+	MaxSize   int64
+	ExpiresAt time.Time
+}
+
+// 移除 path 上的追加租约，返回恢复它的函数，供持久化失败时撤销
+func (s *metaServer) dropLease(path string) (restore func()) {
+	lease, exists := s.leases[path]
+	delete(s.leases, path)
+	return func() {
+		if exists {
+			s.leases[path] = lease
+		}
+	}
+}
+
+// 授予或续期追加租约
+// 当前分片已满或无法写入时换用客户端提议的新分片，旧分片之后不再接受追加，其大小随之固定
+func (s *metaServer) GrantAppendLease(ctx context.Context, req *metapb.AppendLeaseRequest) (*metapb.AppendLease, error) {
+	path := metadata.CleanPath(req.Path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkLocks(ctx, path); err != nil {
+		return nil, err
+	}
+	node, err := s.appendTarget(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lease := s.leases[path]
+	if lease != nil && req.Full != nil && req.Full.ChunkId == lease.Chunk.ChunkID {
+		undo := saveMetadata(node)
+		if _, err := s.commitRecord(path, node, req.Full); err != nil {
+			return nil, err
+		}
+		if err := s.persistOrUndo(undo); err != nil {
+			return nil, err
+		}
+		log.Printf("Append chunk %s of %s is full", lease.Chunk.ChunkID, path)
+		lease = nil
+	}
+	if lease != nil && req.FailedChunkId != "" && req.FailedChunkId == lease.Chunk.ChunkID {
+		log.Printf("Append chunk %s of %s failed, switching to a new chunk", lease.Chunk.ChunkID, path)
+		lease = nil
+	}
+	if lease == nil {
+		maxSize := node.Metadata.ChunkSize
+		if maxSize == 0 {
+			maxSize = req.ChunkSize
+		}
+		if req.Proposed == nil || req.Proposed.ChunkId == "" || req.Proposed.StorageLocation == "" || maxSize <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "a new append chunk is needed but none was proposed")
+		}
+		lease = &appendLease{Chunk: metadata.FileChunkFromProto(req.Proposed), MaxSize: maxSize}
+		s.leases[path] = lease
+		log.Printf("Granted append lease on %s: chunk %s, primary %s", path, lease.Chunk.ChunkID, lease.Chunk.StorageLocation)
+	}
+	lease.ExpiresAt = time.Now().Add(s.leaseDuration)
+	return &metapb.AppendLease{Chunk: lease.Chunk.ToProto(), MaxSize: lease.MaxSize, ExpiresAt: lease.ExpiresAt.UnixNano()}, nil
+}
+
+// 提交记录追加后分片的大小，返回分片在文件中的起始偏移
+func (s *metaServer) CommitRecord(ctx context.Context, req *metapb.CommitRecordRequest) (*metapb.CommitRecordResponse, error) {
+	path := metadata.CleanPath(req.Path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkLocks(ctx, path); err != nil {
+		return nil, err
+	}
+	node, err := s.appendTarget(path)
+	if err != nil {
+		return nil, err
+	}
+	undo := saveMetadata(node)
+	offset, err := s.commitRecord(path, node, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.persistOrUndo(undo); err != nil {
+		return nil, err
+	}
+	return &metapb.CommitRecordResponse{ChunkOffset: offset}, nil
+}
+
+// 保存文件元数据的副本，返回的函数恢复保存时的元数据
+func saveMetadata(node *metadata.FileNode) func() {
+	saved := *node.Metadata
+	saved.Chunks = slices.Clone(saved.Chunks)
+	return func() { *node.Metadata = saved }
+}
+
+// 记录追加的目标文件，调用方需持有锁
+func (s *metaServer) appendTarget(path string) (*metadata.FileNode, error) {
+	node, err := s.tree.Lookup(path)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if node.Metadata.IsDirectory {
+		return nil, toStatus(metadata.ErrIsDirectory)
+	}
+	return node, nil
+}
+
+// 更新分片大小：租约分片首次提交时加入文件末尾，之后只增不减；其他分片的大小已固定
+// 提交的大小不超过已记录的大小时说明记录已被之后的提交覆盖，直接返回偏移。调用方需持有锁并负责持久化
+func (s *metaServer) commitRecord(path string, node *metadata.FileNode, req *metapb.CommitRecordRequest) (int64, error) {
+	if req.Size <= 0 {
+		return 0, status.Error(codes.InvalidArgument, "empty append chunk")
+	}
+	meta := node.Metadata
+	lease := s.leases[path]
+	isLease := lease != nil && lease.Chunk.ChunkID == req.ChunkId
+	var offset int64
+	index := -1
+	for i, chunk := range meta.Chunks {
+		if chunk.ChunkID == req.ChunkId {
+			index = i
+			break
+		}
+		offset += chunk.Size
+	}
+
+	switch {
+	case index >= 0 && req.Size <= meta.Chunks[index].Size:
+		return offset, nil
+	case index >= 0 && isLease:
+		meta.Chunks[index].Size = req.Size
+		meta.Chunks[index].Checksum = req.Checksum
+	case index < 0 && isLease:
+		chunk := lease.Chunk
+		chunk.ChunkNumber = len(meta.Chunks)
+		chunk.Size = req.Size
+		chunk.Checksum = req.Checksum
+		meta.Chunks = append(meta.Chunks, chunk)
+	default:
+		return 0, status.Errorf(codes.Aborted, "chunk %s no longer accepts appends to %s", req.ChunkId, path)
+	}
+	meta.Size = 0
+	for _, chunk := range meta.Chunks {
+		meta.Size += chunk.Size
+	}
+	meta.ModificationTime = time.Now()
+	return offset, nil
+}
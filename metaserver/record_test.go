@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAppendLease(t *testing.T) {
+	proposed := func(id string) *metapb.FileChunk {
+		return &metapb.FileChunk{ChunkId: id, StorageLocation: "node1"}
+	}
+	type step struct {
+		req     *metapb.AppendLeaseRequest
+		commit  *metapb.CommitRecordRequest // 代替 req，提交记录
+		want    string                      // 授予的租约的分片
+		wantErr codes.Code
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		check []string // 最后文件的分片
+	}{
+		{"second writer shares the chunk", []step{
+			{req: &metapb.AppendLeaseRequest{Proposed: proposed("a")}, want: "a"},
+			{req: &metapb.AppendLeaseRequest{Proposed: proposed("b")}, want: "a"},
+			{commit: &metapb.CommitRecordRequest{ChunkId: "a", Size: 5}},
+		}, []string{"x_0", "a"}},
+		{"full chunk is committed and replaced", []step{
+			{req: &metapb.AppendLeaseRequest{Proposed: proposed("a")}, want: "a"},
+			{commit: &metapb.CommitRecordRequest{ChunkId: "a", Size: 5}},
+			{req: &metapb.AppendLeaseRequest{Proposed: proposed("b"), Full: &metapb.CommitRecordRequest{ChunkId: "a", Size: 10}}, want: "b"},
+			{commit: &metapb.CommitRecordRequest{ChunkId: "a", Size: 12}, wantErr: codes.Aborted},
+		}, []string{"x_0", "a"}},
+		{"stale full report keeps the lease", []step{
+			{req: &metapb.AppendLeaseRequest{Proposed: proposed("a")}, want: "a"},
+			{req: &metapb.AppendLeaseRequest{Proposed: proposed("b"), Full: &metapb.CommitRecordRequest{ChunkId: "old", Size: 10}}, want: "a"},
+		}, []string{"x_0"}},
+		{"failed chunk is replaced", []step{
+			{req: &metapb.AppendLeaseRequest{Proposed: proposed("a")}, want: "a"},
+			{req: &metapb.AppendLeaseRequest{Proposed: proposed("b"), FailedChunkId: "a"}, want: "b"},
+			{commit: &metapb.CommitRecordRequest{ChunkId: "a", Size: 3}, wantErr: codes.Aborted},
+			{commit: &metapb.CommitRecordRequest{ChunkId: "b", Size: 3}},
+		}, []string{"x_0", "b"}},
+		{"smaller commit is ignored", []step{
+			{req: &metapb.AppendLeaseRequest{Proposed: proposed("a")}, want: "a"},
+			{commit: &metapb.CommitRecordRequest{ChunkId: "a", Size: 8}},
+			{commit: &metapb.CommitRecordRequest{ChunkId: "a", Size: 4}},
+		}, []string{"x_0", "a"}},
+		{"no proposal", []step{
+			{req: &metapb.AppendLeaseRequest{}, wantErr: codes.InvalidArgument},
+		}, []string{"x_0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			addTestFile(t, s, "/f", testChunks("x", 1, 10))
+			ctx := context.Background()
+			for i, st := range tt.steps {
+				var err error
+				if st.commit != nil {
+					st.commit.Path = "/f"
+					_, err = s.CommitRecord(ctx, st.commit)
+				} else {
+					st.req.Path, st.req.ChunkSize = "/f", 10
+					var lease *metapb.AppendLease
+					lease, err = s.GrantAppendLease(ctx, st.req)
+					if err == nil && lease.Chunk.ChunkId != st.want {
+						t.Fatalf("step %d: lease on chunk %s, want %s", i, lease.Chunk.ChunkId, st.want)
+					}
+				}
+				if status.Code(err) != st.wantErr {
+					t.Fatalf("step %d: %v, want %v", i, err, st.wantErr)
+				}
+			}
+			node, _ := s.tree.Lookup("/f")
+			if got := chunkIDs(node.Metadata.Chunks); !slices.Equal(got, tt.check) {
+				t.Errorf("chunks = %v, want %v", got, tt.check)
+			}
+		})
+	}
+}
+
+// 追加租约的分片在首次提交之前即列为被引用，租约过期后不再列出
+func TestAppendLeaseChunksListed(t *testing.T) {
+	s := newTestServer(t)
+	addTestFile(t, s, "/f", testChunks("x", 1, 10))
+	ctx := context.Background()
+	if _, err := s.GrantAppendLease(ctx, &metapb.AppendLeaseRequest{Path: "/f", ChunkSize: 10, Proposed: &metapb.FileChunk{ChunkId: "a", StorageLocation: "node1"}}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.ListUploads(ctx, &metapb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.AppendChunks) != 1 || resp.AppendChunks[0].ChunkId != "a" {
+		t.Fatalf("append chunks = %v, want [a]", resp.AppendChunks)
+	}
+
+	s.leases["/f"].ExpiresAt = time.Now().Add(-time.Second)
+	if resp, _ = s.ListUploads(ctx, &metapb.Empty{}); len(resp.AppendChunks) != 0 {
+		t.Fatalf("expired lease listed: %v", resp.AppendChunks)
+	}
+}
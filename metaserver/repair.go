@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	"grpc-distributed-fs/placement"
+	pb "grpc-distributed-fs/proto/fs"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// 存储节点的地址（来自块报告）和 gRPC 连接
+type nodeDirectory struct {
+	mu      sync.Mutex
+	addrs   map[string]string    // 节点 ID -> 地址
+	seen    map[string]time.Time // 节点 ID -> 最近一次块报告的时间
+	labels  map[string]map[string]string
+	clients map[string]pb.FileSystemClient
+
+	weights map[string]float64 // 节点容量权重，未配置的节点为 1
+	level   string             // 重新放置副本时须跨越的故障域层级
+	timeout time.Duration      // 超过此时长未收到块报告的节点视为已离开集群，0 表示不检查
+	started time.Time
+}
+
+func newNodeDirectory() *nodeDirectory {
+	return &nodeDirectory{
+		addrs:   make(map[string]string),
+		seen:    make(map[string]time.Time),
+		labels:  make(map[string]map[string]string),
+		clients: make(map[string]pb.FileSystemClient),
+		weights: make(map[string]float64),
+		level:   "host",
+		started: time.Now(),
+	}
+}
+
+// 记录节点地址和收到块报告的时间
+func (d *nodeDirectory) update(nodeID, addr string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.addrs[nodeID] = addr
+	d.seen[nodeID] = time.Now()
+}
+
+// 节点地址，未收到过该节点的块报告时返回 false
+func (d *nodeDirectory) addr(nodeID string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	addr, exists := d.addrs[nodeID]
+	return addr, exists
+}
+
+// 在集群中的节点：有地址且在 timeout 内发送过块报告
+// 元数据服务启动后的 timeout 内按启动时间计算，避免节点尚未报告时被视为离开
+func (d *nodeDirectory) live() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var ids []string
+	for id := range d.addrs {
+		if d.timeout <= 0 || time.Since(maxTime(d.seen[id], d.started)) <= d.timeout {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// 查询节点的拓扑标签，同时确认节点可以连接
+func (d *nodeDirectory) probe(nodeID string) error {
+	addr, exists := d.addr(nodeID)
+	if !exists {
+		return fmt.Errorf("no address for node %s", nodeID)
+	}
+	client, err := d.client(addr)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := client.NodeInfo(ctx, &pb.NodeInfoRequest{})
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.labels[nodeID] = info.Labels
+	d.mu.Unlock()
+	return nil
+}
+
+// 以 ids 中的节点初始化故障域感知的放置策略
+func (d *nodeDirectory) placement(ids []string) *placement.DomainAware {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	nodes := make([]placement.Node, 0, len(ids))
+	for _, id := range ids {
+		weight, exists := d.weights[id]
+		if !exists {
+			weight = 1
+		}
+		nodes = append(nodes, placement.Node{ID: id, Weight: weight, Labels: d.labels[id]})
+	}
+	return placement.NewDomainAware(nodes, d.level)
+}
+
+// 按地址复用到存储节点的连接
+func (d *nodeDirectory) client(addr string) (pb.FileSystemClient, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if client, exists := d.clients[addr]; exists {
+		return client, nil
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	client := pb.NewFileSystemClient(conn)
+	d.clients[addr] = client
+	return client, nil
+}
+
+// 一个待修复的分片
+type repairJob struct {
+	path    string
+	chunk   metadata.FileChunk
+	targets []string // 待写入副本的节点 ID
+}
+
+// 每隔 interval 修复一次待修复的副本
+func (s *metaServer) startRepair(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+			s.repairPending()
+		}
+	}()
+}
+
+// 将待修复的副本从已有副本复制到目标节点，成功后记录新的副本位置
+// 目标节点已离开集群或无法连接时，先按放置策略为副本另选节点
+func (s *metaServer) repairPending() {
+	var jobs []repairJob
+	s.mu.Lock()
+	s.tree.Walk(func(path string, meta *metadata.FileMetadata) {
+		for _, chunk := range meta.Chunks {
+			if len(chunk.Pending) > 0 && len(chunk.Locations()) > 0 {
+				jobs = append(jobs, repairJob{path: path, chunk: chunk, targets: chunk.Pending})
+			}
+		}
+	})
+	s.mu.Unlock()
+
+	repaired, moved := 0, 0
+	candidates := &repairCandidates{nodes: s.nodes}
+	for _, job := range jobs {
+		replaced := s.replaceTargets(job, candidates)
+		if len(replaced) > 0 {
+			moved += len(replaced)
+			job.targets = slices.Clone(job.targets)
+			for i, target := range job.targets {
+				if next, exists := replaced[target]; exists {
+					job.targets[i] = next
+				}
+			}
+		}
+
+		started := time.Now()
+		acked := s.repair(job)
+		if len(acked) == 0 && len(replaced) == 0 {
+			continue
+		}
+		s.mu.Lock()
+		if node, err := s.tree.Lookup(job.path); err == nil {
+			for i := range node.Metadata.Chunks {
+				chunk := &node.Metadata.Chunks[i]
+				if chunk.ChunkID != job.chunk.ChunkID {
+					continue
+				}
+				for old, next := range replaced {
+					if slices.Contains(chunk.Pending, old) {
+						chunk.Pending = slices.DeleteFunc(chunk.Pending, func(id string) bool { return id == old })
+						chunk.AddPending(next)
+					}
+				}
+				for _, nodeID := range acked {
+					if chunk.AddLocation(nodeID) {
+						repaired++
+						if s.repaired[chunk.ChunkID] == nil {
+							s.repaired[chunk.ChunkID] = make(map[string]time.Time)
+						}
+						s.repaired[chunk.ChunkID][nodeID] = started
+					}
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	if repaired > 0 || moved > 0 {
+		log.Printf("Repaired %d replica(s), moved %d pending replica(s) to other nodes", repaired, moved)
+		s.mu.Lock()
+		s.persist()
+		s.mu.Unlock()
+	}
+}
+
+// 一轮修复中可以接收副本的节点，按需探测，每个节点至多探测一次
+type repairCandidates struct {
+	nodes     *nodeDirectory
+	live      []string
+	reachable map[string]bool
+}
+
+// 节点是否在集群中且可以连接
+func (c *repairCandidates) usable(nodeID string) bool {
+	if c.reachable == nil {
+		c.live = c.nodes.live()
+		c.reachable = make(map[string]bool)
+	}
+	if !slices.Contains(c.live, nodeID) {
+		return false
+	}
+	ok, probed := c.reachable[nodeID]
+	if !probed {
+		err := c.nodes.probe(nodeID)
+		if err != nil {
+			log.Printf("Storage node %s unreachable: %v", nodeID, err)
+		}
+		ok = err == nil
+		c.reachable[nodeID] = ok
+	}
+	return ok
+}
+
+// 为目标节点不可用的待修复副本另选节点，返回原目标 -> 新目标
+// 新节点避开分片已有副本和其余目标所在的故障域，没有合适的节点时保留原目标，下一轮再试
+func (s *metaServer) replaceTargets(job repairJob, candidates *repairCandidates) map[string]string {
+	var gone, keep []string
+	for _, target := range job.targets {
+		if candidates.usable(target) {
+			keep = append(keep, target)
+		} else {
+			gone = append(gone, target)
+		}
+	}
+	if len(gone) == 0 {
+		return nil
+	}
+
+	var usable []string
+	for _, nodeID := range candidates.nodes.live() {
+		if candidates.usable(nodeID) {
+			usable = append(usable, nodeID)
+		}
+	}
+	existing := append(job.chunk.Locations(), keep...)
+	picks := candidates.nodes.placement(usable).PlaceMore(job.chunk.ChunkID, existing, len(gone))
+	if len(picks) == 0 {
+		return nil
+	}
+	replaced := make(map[string]string)
+	for i, next := range picks {
+		replaced[gone[i]] = next
+		log.Printf("Moving pending replica of %s chunk %d from node %s to node %s", job.path, job.chunk.ChunkNumber, gone[i], next)
+	}
+	return replaced
+}
+
+// 依次请求已有副本所在节点复制分片，返回写入成功的节点 ID
+func (s *metaServer) repair(job repairJob) []string {
+	var targets []string
+	byAddr := make(map[string]string)
+	for _, nodeID := range job.targets {
+		if addr, exists := s.nodes.addr(nodeID); exists {
+			targets = append(targets, addr)
+			byAddr[addr] = nodeID
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	for _, source := range job.chunk.Locations() {
+		addr, exists := s.nodes.addr(source)
+		if !exists {
+			continue
+		}
+		client, err := s.nodes.client(addr)
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		resp, err := client.Replicate(ctx, &pb.ReplicateRequest{Filename: job.chunk.ChunkID, Targets: targets})
+		cancel()
+		if err != nil {
+			log.Printf("Failed to repair %s chunk %d from node %s: %v", job.path, job.chunk.ChunkNumber, source, err)
+			continue
+		}
+		var acked []string
+		for _, addr := range resp.Acked {
+			acked = append(acked, byAddr[addr])
+		}
+		return acked
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	pb "grpc-distributed-fs/proto/fs"
+	metapb "grpc-distributed-fs/proto/meta"
+)
+
+// 复制请求总是成功的存储节点，记录收到的复制请求
+type replicaNode struct {
+	pb.UnimplementedFileSystemServer
+	id         string
+	mu         sync.Mutex
+	replicated []string // 复制的分片 ID
+}
+
+func (n *replicaNode) NodeInfo(ctx context.Context, req *pb.NodeInfoRequest) (*pb.NodeInfoResponse, error) {
+	return &pb.NodeInfoResponse{Id: n.id}, nil
+}
+
+func (n *replicaNode) Replicate(ctx context.Context, req *pb.ReplicateRequest) (*pb.ReplicateResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.replicated = append(n.replicated, req.Filename)
+	return &pb.ReplicateResponse{Acked: req.Targets}, nil
+}
+
+// 修复把等待中的副本写入目标节点并记为副本位置
+// 修复开始前生成的块报告中没有该副本，不能因此再次移除它
+func TestRepairPending(t *testing.T) {
+	s := newTestServer(t)
+	source := &replicaNode{id: "node1"}
+	startStorageNode(t, s, "node1", source)
+	startStorageNode(t, s, "node2", &replicaNode{id: "node2"})
+	chunks := testChunks("f", 2, 10)
+	chunks[1].Pending = []string{"node2"}
+	meta := addTestFile(t, s, "/f", chunks)
+	meta.ModificationTime = time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-time.Second) // 修复开始前生成的报告
+
+	s.repairPending()
+	source.mu.Lock()
+	replicated := source.replicated
+	source.mu.Unlock()
+	if want := []string{"f_1"}; !slices.Equal(replicated, want) {
+		t.Fatalf("replicated %v, want %v", replicated, want)
+	}
+	locations := func() []string {
+		file, err := s.tree.Lookup("/f")
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunk := file.Metadata.Chunks[1]
+		if len(chunk.Pending) > 0 {
+			return append(chunk.Locations(), "pending:"+chunk.Pending[0])
+		}
+		return chunk.Locations()
+	}
+	if got, want := locations(), []string{"node1", "node2"}; !slices.Equal(got, want) {
+		t.Fatalf("after repair: %v, want %v", got, want)
+	}
+
+	report := func(generatedAt time.Time) *metapb.BlockReportResponse {
+		t.Helper()
+		resp, err := s.BlockReport(context.Background(), &metapb.BlockReportRequest{NodeId: "node2", GeneratedAt: generatedAt.UnixNano()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	if resp := report(stale); resp.Removed != 0 {
+		t.Errorf("stale report removed %d replica(s)", resp.Removed)
+	}
+	if got, want := locations(), []string{"node1", "node2"}; !slices.Equal(got, want) {
+		t.Errorf("after stale report: %v, want %v", got, want)
+	}
+	// 修复之后生成的报告中仍没有该副本，说明副本确实丢失
+	if resp := report(time.Now()); resp.Removed != 1 {
+		t.Errorf("fresh report removed %d replica(s), want 1", resp.Removed)
+	}
+	if got, want := locations(), []string{"node1", "pending:node2"}; !slices.Equal(got, want) {
+		t.Errorf("after fresh report: %v, want %v", got, want)
+	}
+}
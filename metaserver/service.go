@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type metaServer struct {
+	metapb.UnimplementedMetadataServer
+
+	mu           sync.Mutex
+	tree         *metadata.FileTree
+	snapshot     string                          // 快照文件路径
+	missing      map[string]map[string]bool      // 分片 ID -> 已丢失副本所在节点
+	repaired     map[string]map[string]time.Time // 分片 ID -> 修复写入副本的节点 -> 开始写入的时间
+	nodes        *nodeDirectory                  // 存储节点地址，用于修复副本和回收分片
+	uploads      map[string]*upload              // 上传 ID -> 未提交的上传
+	uploadTTL    time.Duration                   // 未提交的上传最后一次登记分片后的有效期
+	multipartTTL time.Duration                   // 分段上传最后一次登记分片后的有效期
+
+	leases        map[string]*appendLease // 路径 -> 追加租约
+	leaseDuration time.Duration
+
+	locks map[string]map[string]*lockHolder // 路径 -> 持有者 -> 路径租约
+}
+
+func newMetaServer(snapshot string, uploadTTL, multipartTTL, leaseDuration time.Duration) (*metaServer, error) {
+	tree, uploads, err := loadSnapshot(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &metaServer{
+		tree:         tree,
+		snapshot:     snapshot,
+		missing:      make(map[string]map[string]bool),
+		repaired:     make(map[string]map[string]time.Time),
+		nodes:        newNodeDirectory(),
+		uploads:      uploads,
+		uploadTTL:    uploadTTL,
+		multipartTTL: multipartTTL,
+
+		leases:        make(map[string]*appendLease),
+		leaseDuration: leaseDuration,
+
+		locks: make(map[string]map[string]*lockHolder),
+	}, nil
+}
+
+// 持久化文件树和未提交的上传，调用方需持有锁
+func (s *metaServer) persist() error {
+	if err := saveSnapshot(s.snapshot, s.tree, s.uploads); err != nil {
+		log.Printf("Error saving metadata snapshot: %v", err)
+		return err
+	}
+	return nil
+}
+
+// 持久化内存中的修改，失败时调用 undo 撤销这些修改，使内存中的状态与快照保持一致，调用方需持有锁
+func (s *metaServer) persistOrUndo(undo func()) error {
+	if err := s.persist(); err != nil {
+		undo()
+		return err
+	}
+	return nil
+}
+
+// 创建目录
+func (s *metaServer) Mkdir(ctx context.Context, req *metapb.PathRequest) (*metapb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkLocks(ctx, req.Path); err != nil {
+		return nil, err
+	}
+	if err := s.tree.MkdirAt(req.Path); err != nil {
+		return nil, toStatus(err)
+	}
+	return &metapb.Empty{}, s.persistOrUndo(func() { s.tree.RemoveAt(req.Path) })
+}
+
+// 列出目录
+func (s *metaServer) List(ctx context.Context, req *metapb.PathRequest) (*metapb.ListResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.tree.ListAt(req.Path)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &metapb.ListResponse{}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, entry.ToProto())
+	}
+	return resp, nil
+}
+
+// 获取文件元数据
+func (s *metaServer) Stat(ctx context.Context, req *metapb.PathRequest) (*metapb.FileMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, err := s.tree.Lookup(req.Path)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return node.Metadata.ToProto(), nil
+}
+
+// 添加文件
+func (s *metaServer) AddFile(ctx context.Context, req *metapb.AddFileRequest) (*metapb.Empty, error) {
+	meta := metadata.FileMetadataFromProto(req.Metadata)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkLocks(ctx, req.Dir+"/"+meta.Name); err != nil {
+		return nil, err
+	}
+	if err := s.tree.AddFileAt(req.Dir, meta); err != nil {
+		return nil, toStatus(err)
+	}
+	return &metapb.Empty{}, s.persistOrUndo(func() { s.tree.RemoveAt(req.Dir + "/" + meta.Name) })
+}
+
+// 删除文件或空目录
+func (s *metaServer) RemoveFile(ctx context.Context, req *metapb.PathRequest) (*metapb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkTreeLocks(ctx, req.Path); err != nil {
+		return nil, err
+	}
+	node, err := s.tree.Lookup(req.Path)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err := s.tree.RemoveAt(req.Path); err != nil {
+		return nil, toStatus(err)
+	}
+	restoreLease := s.dropLease(metadata.CleanPath(req.Path))
+	return &metapb.Empty{}, s.persistOrUndo(func() {
+		node.Parent.Children[node.Metadata.Name] = node
+		restoreLease()
+	})
+}
+
+// 重命名或移动
+func (s *metaServer) Rename(ctx context.Context, req *metapb.RenameRequest) (*metapb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 移动到已存在的目录下时，实际的目标路径在该目录下
+	to := req.To
+	if target, err := s.tree.Lookup(to); err == nil && target.Metadata.IsDirectory {
+		_, name := metadata.SplitPath(req.From)
+		to = metadata.CleanPath(to + "/" + name)
+	}
+	if err := s.checkTreeLocks(ctx, req.From, req.To, to); err != nil {
+		return nil, err
+	}
+	if err := s.tree.RenameAt(req.From, req.To); err != nil {
+		return nil, toStatus(err)
+	}
+	restoreLease := s.dropLease(metadata.CleanPath(req.From))
+	return &metapb.Empty{}, s.persistOrUndo(func() {
+		s.tree.RenameAt(to, req.From)
+		restoreLease()
+	})
+}
+
+// 遍历目录树
+func (s *metaServer) Walk(req *metapb.PathRequest, stream metapb.Metadata_WalkServer) error {
+	var entries []*metapb.WalkEntry
+	s.mu.Lock()
+	err := s.tree.WalkAt(req.Path, func(path string, meta *metadata.FileMetadata) {
+		entries = append(entries, &metapb.WalkEntry{Path: path, Metadata: meta.ToProto()})
+	})
+	s.mu.Unlock()
+	if err != nil {
+		return toStatus(err)
+	}
+
+	for _, entry := range entries {
+		if err := stream.Send(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 获取目录下新文件的默认值
+func (s *metaServer) GetDefaults(ctx context.Context, req *metapb.PathRequest) (*metapb.Defaults, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defaults, err := s.tree.DefaultsAt(req.Path)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &metapb.Defaults{ChunkSize: defaults.ChunkSize, Replicas: int32(defaults.Replicas), WriteQuorum: int32(defaults.WriteQuorum)}, nil
+}
+
+// 设置目录下新文件的默认值
+func (s *metaServer) SetDefaults(ctx context.Context, req *metapb.SetDefaultsRequest) (*metapb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, err := s.tree.Lookup(req.Path)
+	if err != nil {
+		return nil, toStatus(metadata.ErrDirNotFound)
+	}
+	previous := metadata.Defaults{ChunkSize: node.Metadata.ChunkSize, Replicas: node.Metadata.Replicas, WriteQuorum: node.Metadata.WriteQuorum}
+	defaults := metadata.Defaults{ChunkSize: req.ChunkSize, Replicas: int(req.Replicas), WriteQuorum: int(req.WriteQuorum)}
+	if err := s.tree.SetDefaultsAt(req.Path, defaults); err != nil {
+		return nil, toStatus(err)
+	}
+	return &metapb.Empty{}, s.persistOrUndo(func() { s.tree.SetDefaultsAt(req.Path, previous) })
+}
+
+// 将目录树错误转换为 gRPC 状态码，便于客户端区分错误类型
+func toStatus(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, metadata.ErrFileNotFound), errors.Is(err, metadata.ErrDirNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, metadata.ErrFileExists), errors.Is(err, metadata.ErrDirExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, metadata.ErrNotDirectory), errors.Is(err, metadata.ErrNotEmpty), errors.Is(err, metadata.ErrIsDirectory):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	metapb "grpc-distributed-fs/proto/meta"
+)
+
+// 快照写入临时目录的元数据服务
+func newTestServer(t *testing.T) *metaServer {
+	t.Helper()
+	s, err := newMetaServer(filepath.Join(t.TempDir(), "meta.json"), time.Hour, time.Hour, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// 以 fileID 写入的 n 个分片，每个大小为 size
+func testChunks(fileID string, n int, size int64) []metadata.FileChunk {
+	var chunks []metadata.FileChunk
+	for i := range n {
+		chunks = append(chunks, metadata.FileChunk{
+			ChunkID:         fmt.Sprintf("%s_%d", fileID, i),
+			FileID:          fileID,
+			ChunkNumber:     i,
+			Size:            size,
+			StorageLocation: "node1",
+		})
+	}
+	return chunks
+}
+
+// 直接在文件树中添加文件，不经过上传
+func addTestFile(t *testing.T, s *metaServer, path string, chunks []metadata.FileChunk) *metadata.FileMetadata {
+	t.Helper()
+	dir, name := metadata.SplitPath(path)
+	meta := &metadata.FileMetadata{Name: name, Chunks: chunks, ModificationTime: time.Now()}
+	for _, chunk := range chunks {
+		meta.Size += chunk.Size
+	}
+	if err := s.tree.AddFileAt(dir, meta); err != nil {
+		t.Fatal(err)
+	}
+	return meta
+}
+
+func chunkIDs(chunks []metadata.FileChunk) []string {
+	var ids []string
+	for _, chunk := range chunks {
+		ids = append(ids, chunk.ChunkID)
+	}
+	return ids
+}
+
+// 持久化失败时请求返回错误，内存中的文件树和上传保持请求之前的状态
+func TestPersistFailureUndo(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, s *metaServer)
+		call  func(s *metaServer) error
+		check func(t *testing.T, s *metaServer)
+	}{
+		{
+			name: "mkdir",
+			call: func(s *metaServer) error {
+				_, err := s.Mkdir(ctx, &metapb.PathRequest{Path: "/d"})
+				return err
+			},
+			check: func(t *testing.T, s *metaServer) {
+				if _, err := s.tree.Lookup("/d"); err == nil {
+					t.Error("/d exists")
+				}
+			},
+		},
+		{
+			name: "remove",
+			setup: func(t *testing.T, s *metaServer) {
+				addTestFile(t, s, "/f", testChunks("f", 1, 10))
+				s.leases["/f"] = &appendLease{}
+			},
+			call: func(s *metaServer) error {
+				_, err := s.RemoveFile(ctx, &metapb.PathRequest{Path: "/f"})
+				return err
+			},
+			check: func(t *testing.T, s *metaServer) {
+				if _, err := s.tree.Lookup("/f"); err != nil {
+					t.Errorf("/f: %v", err)
+				}
+				if s.leases["/f"] == nil {
+					t.Error("append lease removed")
+				}
+			},
+		},
+		{
+			name: "rename into directory",
+			setup: func(t *testing.T, s *metaServer) {
+				addTestFile(t, s, "/f", testChunks("f", 1, 10))
+				s.tree.MkdirAt("/d")
+				s.leases["/f"] = &appendLease{}
+			},
+			call: func(s *metaServer) error {
+				_, err := s.Rename(ctx, &metapb.RenameRequest{From: "/f", To: "/d"})
+				return err
+			},
+			check: func(t *testing.T, s *metaServer) {
+				if _, err := s.tree.Lookup("/f"); err != nil {
+					t.Errorf("/f: %v", err)
+				}
+				if _, err := s.tree.Lookup("/d/f"); err == nil {
+					t.Error("/d/f exists")
+				}
+				if s.leases["/f"] == nil {
+					t.Error("append lease removed")
+				}
+			},
+		},
+		{
+			name: "set defaults",
+			setup: func(t *testing.T, s *metaServer) {
+				s.tree.MkdirAt("/d")
+				s.tree.SetDefaultsAt("/d", metadata.Defaults{ChunkSize: 100, Replicas: 2})
+			},
+			call: func(s *metaServer) error {
+				_, err := s.SetDefaults(ctx, &metapb.SetDefaultsRequest{Path: "/d", ChunkSize: 200, Replicas: 3, WriteQuorum: 2})
+				return err
+			},
+			check: func(t *testing.T, s *metaServer) {
+				defaults, _ := s.tree.DefaultsAt("/d")
+				if want := (metadata.Defaults{ChunkSize: 100, Replicas: 2}); defaults != want {
+					t.Errorf("defaults = %+v, want %+v", defaults, want)
+				}
+			},
+		},
+		{
+			name: "begin upload",
+			call: func(s *metaServer) error {
+				_, err := s.BeginUpload(ctx, &metapb.BeginUploadRequest{Path: "/f", UploadId: "u"})
+				return err
+			},
+			check: func(t *testing.T, s *metaServer) {
+				if len(s.uploads) > 0 {
+					t.Errorf("%d upload(s) registered", len(s.uploads))
+				}
+			},
+		},
+		{
+			name: "commit upload",
+			setup: func(t *testing.T, s *metaServer) {
+				addTestFile(t, s, "/f", testChunks("old", 2, 10))
+				beginTestUpload(t, s, &metapb.BeginUploadRequest{Path: "/f", UploadId: "new", Overwrite: true}, testChunks("new", 1, 10))
+				s.leases["/f"] = &appendLease{}
+			},
+			call: func(s *metaServer) error {
+				_, err := s.CommitUpload(ctx, &metapb.CommitUploadRequest{UploadId: "new", Chunks: 1, Size: 10})
+				return err
+			},
+			check: func(t *testing.T, s *metaServer) {
+				node, _ := s.tree.Lookup("/f")
+				if got := chunkIDs(node.Metadata.Chunks); fmt.Sprint(got) != "[old_0 old_1]" {
+					t.Errorf("chunks = %v, want [old_0 old_1]", got)
+				}
+				if s.uploads["new"] == nil {
+					t.Error("upload removed")
+				}
+				if s.leases["/f"] == nil {
+					t.Error("append lease removed")
+				}
+			},
+		},
+		{
+			name: "abort upload",
+			setup: func(t *testing.T, s *metaServer) {
+				beginTestUpload(t, s, &metapb.BeginUploadRequest{Path: "/f", UploadId: "u"}, nil)
+			},
+			call: func(s *metaServer) error {
+				_, err := s.AbortUpload(ctx, &metapb.UploadRequest{UploadId: "u"})
+				return err
+			},
+			check: func(t *testing.T, s *metaServer) {
+				if s.uploads["u"] == nil {
+					t.Error("upload removed")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(t, s)
+			}
+			// 快照所在目录不存在，之后的持久化都会失败
+			s.snapshot = filepath.Join(t.TempDir(), "missing", "meta.json")
+			if err := tt.call(s); err == nil {
+				t.Fatal("request succeeded without persisting")
+			}
+			tt.check(t, s)
+			if _, err := os.Stat(s.snapshot); err == nil {
+				t.Error("snapshot written")
+			}
+		})
+	}
+}
+
+// 开始上传并登记已写入的分片
+func beginTestUpload(t *testing.T, s *metaServer, req *metapb.BeginUploadRequest, chunks []metadata.FileChunk) {
+	t.Helper()
+	if _, err := s.BeginUpload(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range chunks {
+		if _, err := s.AddChunk(context.Background(), &metapb.AddChunkRequest{UploadId: req.UploadId, Chunk: chunk.ToProto()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
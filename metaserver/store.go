@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+
+	"grpc-distributed-fs/metadata"
+)
+
+// 快照中的一条记录
+type snapshotEntry struct {
+	Path     string
+	Metadata *metadata.FileMetadata
+}
+
+// 快照：文件树和未提交的上传，写入同一个文件，提交上传时两者一起更新
+type snapshot struct {
+	Entries []snapshotEntry
+	Uploads map[string]*upload `json:",omitempty"`
+}
+
+// 从快照文件恢复文件树和未提交的上传，文件不存在时返回空树
+// 兼容只包含文件树记录数组的旧快照
+func loadSnapshot(path string) (*metadata.FileTree, map[string]*upload, error) {
+	tree := metadata.NewFileTree()
+	uploads := make(map[string]*upload)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tree, uploads, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var snap snapshot
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &snap.Entries)
+	} else {
+		err = json.Unmarshal(data, &snap)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if snap.Uploads != nil {
+		uploads = snap.Uploads
+	}
+	if err := buildTree(tree, snap.Entries); err != nil {
+		return nil, nil, err
+	}
+	return tree, uploads, nil
+}
+
+// 按快照记录重建文件树
+func buildTree(tree *metadata.FileTree, entries []snapshotEntry) error {
+	// 快照按路径排序，父目录总在子节点之前
+	for _, entry := range entries {
+		if !entry.Metadata.IsDirectory {
+			dir, _ := metadata.SplitPath(entry.Path)
+			if err := tree.AddFileAt(dir, entry.Metadata); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tree.MkdirAt(entry.Path); err != nil {
+			return err
+		}
+		node, err := tree.Lookup(entry.Path)
+		if err != nil {
+			return err
+		}
+		node.Metadata = entry.Metadata
+	}
+	return nil
+}
+
+// 将文件树和未提交的上传写入快照文件，先写临时文件再重命名，保证快照完整
+func saveSnapshot(path string, tree *metadata.FileTree, uploads map[string]*upload) error {
+	snap := snapshot{Uploads: uploads}
+	tree.Walk(func(p string, meta *metadata.FileMetadata) {
+		if p != "/" {
+			snap.Entries = append(snap.Entries, snapshotEntry{Path: p, Metadata: meta})
+		}
+	})
+	sort.Slice(snap.Entries, func(i, j int) bool { return snap.Entries[i].Path < snap.Entries[j].Path })
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 未提交的上传，提交前不出现在文件树中
+type upload struct {
+	ID           string
+	Path         string
+	ChunkSize    int64
+	Replicas     int
+	WriteQuorum  int
+	Overwrite    bool                       // 提交时替换已存在的文件
+	Keep         int                        // 替换时保留原文件的前 Keep 个分片
+	BaseModTime  time.Time                  // 非零时，原文件在此之后被修改过则提交失败
+	Chunks       map[int]metadata.FileChunk // 分片编号 -> 已写入的分片
+	CreationTime time.Time
+	ExpiresAt    time.Time
+
+	Multipart bool             // 分段上传：分片登记在 Parts 中，Chunks 为空
+	Parts     map[string]*part `json:",omitempty"` // 分段写入标识符 -> 分段
+}
+
+func (u *upload) toProto() *metapb.UploadInfo {
+	info := &metapb.UploadInfo{
+		UploadId:    u.ID,
+		Path:        u.Path,
+		ExpiresAt:   u.ExpiresAt.UnixNano(),
+		Multipart:   u.Multipart,
+		ChunkSize:   u.ChunkSize,
+		Replicas:    int32(u.Replicas),
+		WriteQuorum: int32(u.WriteQuorum),
+	}
+	for _, chunk := range u.chunkList() {
+		info.Chunks = append(info.Chunks, chunk.ToProto())
+	}
+	for _, p := range u.committedParts() {
+		info.Parts = append(info.Parts, p.toProto())
+	}
+	return info
+}
+
+// 已登记的分片，按编号排序；分段上传中为全部分段的分片，按分段编号排序
+func (u *upload) chunkList() []metadata.FileChunk {
+	if u.Multipart {
+		var chunks []metadata.FileChunk
+		for _, p := range u.partList() {
+			chunks = append(chunks, sortedChunks(p.Chunks)...)
+		}
+		return chunks
+	}
+	return sortedChunks(u.Chunks)
+}
+
+// 按编号排序的分片
+func sortedChunks(byNumber map[int]metadata.FileChunk) []metadata.FileChunk {
+	numbers := make([]int, 0, len(byNumber))
+	for number := range byNumber {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	chunks := make([]metadata.FileChunk, 0, len(numbers))
+	for _, number := range numbers {
+		chunks = append(chunks, byNumber[number])
+	}
+	return chunks
+}
+
+// 上传的有效期：分段上传的各分段可能间隔很久才写入，使用单独的有效期
+func (s *metaServer) renewUpload(u *upload) {
+	ttl := s.uploadTTL
+	if u.Multipart {
+		ttl = s.multipartTTL
+	}
+	u.ExpiresAt = time.Now().Add(ttl)
+}
+
+// 查找未提交的上传，不存在或已过期时返回 NotFound
+func (s *metaServer) lookupUpload(id string) (*upload, error) {
+	u, exists := s.uploads[id]
+	// 已过期但尚未回收的上传同样不可用，其分片随时可能被回收
+	if !exists || time.Now().After(u.ExpiresAt) {
+		return nil, status.Errorf(codes.NotFound, "upload %s not found or expired", id)
+	}
+	return u, nil
+}
+
+// 开始上传：检查目标路径可用（覆盖时可以是已存在的文件），登记上传；同一上传再次开始时续期
+func (s *metaServer) BeginUpload(ctx context.Context, req *metapb.BeginUploadRequest) (*metapb.UploadInfo, error) {
+	if req.UploadId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing upload id")
+	}
+	if req.Keep < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid keep count %d", req.Keep)
+	}
+	path := metadata.CleanPath(req.Path)
+	dir, _ := metadata.SplitPath(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkLocks(ctx, path); err != nil {
+		return nil, err
+	}
+	parent, err := s.tree.Lookup(dir)
+	if err != nil {
+		return nil, toStatus(metadata.ErrDirNotFound)
+	}
+	if !parent.Metadata.IsDirectory {
+		return nil, toStatus(metadata.ErrNotDirectory)
+	}
+	if node, err := s.tree.Lookup(path); err == nil {
+		if !req.Overwrite {
+			return nil, toStatus(metadata.ErrFileExists)
+		}
+		if node.Metadata.IsDirectory {
+			return nil, toStatus(metadata.ErrIsDirectory)
+		}
+	} else if req.Keep > 0 {
+		return nil, toStatus(err)
+	}
+	if req.Multipart && (req.Keep > 0 || req.BaseModificationTime != 0) {
+		return nil, status.Error(codes.InvalidArgument, "multipart uploads cannot update part of a file")
+	}
+
+	u, exists := s.uploads[req.UploadId]
+	if exists && u.Path != path {
+		return nil, status.Errorf(codes.InvalidArgument, "upload %s targets %s", req.UploadId, u.Path)
+	}
+	if !exists {
+		u = &upload{
+			ID:           req.UploadId,
+			Path:         path,
+			ChunkSize:    req.ChunkSize,
+			Replicas:     int(req.Replicas),
+			WriteQuorum:  int(req.WriteQuorum),
+			Overwrite:    req.Overwrite,
+			Keep:         int(req.Keep),
+			Chunks:       make(map[int]metadata.FileChunk),
+			CreationTime: time.Now(),
+			Multipart:    req.Multipart,
+		}
+		if u.Multipart {
+			u.Parts = make(map[string]*part)
+		}
+		if req.BaseModificationTime != 0 {
+			u.BaseModTime = time.Unix(0, req.BaseModificationTime)
+		}
+		s.uploads[u.ID] = u
+	}
+	s.renewUpload(u)
+	err = s.persistOrUndo(func() {
+		if !exists {
+			delete(s.uploads, u.ID)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u.toProto(), nil
+}
+
+// 登记已写入的分片并续期，分段上传中登记到所属的分段
+// 不立即持久化：元数据服务重启后丢失的登记只会让对应分片成为孤儿，由垃圾回收清理
+func (s *metaServer) AddChunk(ctx context.Context, req *metapb.AddChunkRequest) (*metapb.UploadInfo, error) {
+	if req.Chunk == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing chunk")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.lookupUpload(req.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	chunk := metadata.FileChunkFromProto(req.Chunk)
+	switch {
+	case u.Multipart:
+		if err := u.addPartChunk(req.PartId, int(req.PartNumber), chunk); err != nil {
+			return nil, err
+		}
+	case req.PartId != "":
+		return nil, status.Errorf(codes.InvalidArgument, "upload %s is not a multipart upload", u.ID)
+	default:
+		u.Chunks[chunk.ChunkNumber] = chunk
+	}
+	s.renewUpload(u)
+	return &metapb.UploadInfo{UploadId: u.ID, Path: u.Path, ExpiresAt: u.ExpiresAt.UnixNano()}, nil
+}
+
+// 提交上传：校验分片齐全后将文件加入文件树，覆盖时原子地替换原文件的分片列表
+// 更新文件中间的分片时，写入的分片替换原文件中对应的一段，其后的分片沿用原文件并重新编号
+// 文件树和上传记录一起持久化，被替换的分片在后台回收
+func (s *metaServer) CommitUpload(ctx context.Context, req *metapb.CommitUploadRequest) (*metapb.FileMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.lookupUpload(req.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLocks(ctx, u.Path); err != nil {
+		return nil, err
+	}
+	if u.Multipart {
+		return nil, status.Errorf(codes.FailedPrecondition, "upload %s is a multipart upload", u.ID)
+	}
+
+	// 被替换的文件
+	var old *metadata.FileNode
+	if u.Overwrite {
+		if node, err := s.tree.Lookup(u.Path); err == nil {
+			old = node
+		}
+	}
+	if u.Keep > 0 || !u.BaseModTime.IsZero() {
+		switch {
+		case old == nil:
+			return nil, status.Errorf(codes.Aborted, "%s was removed during the update", u.Path)
+		case !u.BaseModTime.IsZero() && !old.Metadata.ModificationTime.Equal(u.BaseModTime):
+			return nil, status.Errorf(codes.Aborted, "%s was modified during the update", u.Path)
+		case u.Keep > len(old.Metadata.Chunks):
+			return nil, status.Errorf(codes.Aborted, "%s has fewer than %d chunks", u.Path, u.Keep)
+		}
+	}
+	if old != nil && old.Metadata.IsDirectory {
+		return nil, toStatus(metadata.ErrIsDirectory)
+	}
+
+	count, written := int(req.Chunks), int(req.Written)
+	if written == 0 {
+		written = count - u.Keep
+	}
+	// 编号在 [Keep, tail) 的分片由本次上传写入，之后的分片为原文件的最后 count-tail 个分片
+	tail := u.Keep + written
+	if u.Keep < 0 || written < 0 || tail > count {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid chunk range: %d written after %d kept, %d in total", written, u.Keep, count)
+	}
+	var oldChunks []metadata.FileChunk
+	if old != nil {
+		oldChunks = old.Metadata.Chunks
+	}
+	if u.Keep > len(oldChunks) {
+		return nil, status.Errorf(codes.Aborted, "%s has fewer than %d chunks", u.Path, u.Keep)
+	}
+	shift := len(oldChunks) - (count - tail) // 原文件中沿用的末尾分片的起始编号
+	if shift < u.Keep {
+		return nil, status.Errorf(codes.Aborted, "%s has fewer than %d chunks", u.Path, u.Keep+count-tail)
+	}
+	if len(u.Chunks) != written {
+		return nil, status.Errorf(codes.FailedPrecondition, "upload incomplete: %d of %d chunks recorded", len(u.Chunks), written)
+	}
+	chunks := make([]metadata.FileChunk, count)
+	var size int64
+	for number := range count {
+		if number < u.Keep {
+			chunks[number] = oldChunks[number]
+			size += chunks[number].Size
+			continue
+		}
+		if number >= tail {
+			chunks[number] = oldChunks[shift+number-tail]
+			chunks[number].ChunkNumber = number
+			size += chunks[number].Size
+			continue
+		}
+		chunk, exists := u.Chunks[number]
+		if !exists {
+			return nil, status.Errorf(codes.FailedPrecondition, "upload incomplete: chunk %d not recorded", number)
+		}
+		chunks[number] = chunk
+		size += chunk.Size
+	}
+	if size != req.Size {
+		return nil, status.Errorf(codes.FailedPrecondition, "upload incomplete: %d of %d bytes recorded", size, req.Size)
+	}
+
+	var replaced []metadata.FileChunk
+	if old != nil {
+		if tail < count {
+			replaced = oldChunks[u.Keep:shift]
+		} else {
+			replaced = oldChunks[u.Keep:]
+		}
+		replaced = unreferenced(replaced, chunks)
+	}
+	meta, err := s.installUpload(u, old, chunks, size)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Committed upload %s: %s, %d chunk(s), %d replaced", u.ID, u.Path, count, len(replaced))
+	if len(replaced) > 0 {
+		go s.reclaim(u.Path, replaced)
+	}
+	return meta.ToProto(), nil
+}
+
+// replaced 中不再被 chunks 引用的分片
+// 续传沿用上次的文件标识符时，新文件可能与被替换的文件引用同一分片，这些分片不能回收
+func unreferenced(replaced, chunks []metadata.FileChunk) []metadata.FileChunk {
+	live := make(map[string]bool, len(chunks))
+	for _, chunk := range chunks {
+		live[chunk.ChunkID] = true
+	}
+	var result []metadata.FileChunk
+	for _, chunk := range replaced {
+		if !chunk.IsHole() && !live[chunk.ChunkID] {
+			result = append(result, chunk)
+		}
+	}
+	return result
+}
+
+// 将上传提交为文件：新建文件，或替换 old 的元数据并保留其创建时间；删除上传记录后持久化
+// 调用方需持有锁，并负责回收被替换的分片
+func (s *metaServer) installUpload(u *upload, old *metadata.FileNode, chunks []metadata.FileChunk, size int64) (*metadata.FileMetadata, error) {
+	now := time.Now()
+	dir, name := metadata.SplitPath(u.Path)
+	meta := &metadata.FileMetadata{
+		Name:             name,
+		Size:             size,
+		CreationTime:     now,
+		ModificationTime: now,
+		Chunks:           chunks,
+		ChunkSize:        u.ChunkSize,
+		Replicas:         u.Replicas,
+		WriteQuorum:      u.WriteQuorum,
+	}
+	var previous *metadata.FileMetadata
+	if old != nil {
+		previous = old.Metadata
+		meta.CreationTime = previous.CreationTime
+		old.Metadata = meta
+	} else if err := s.tree.AddFileAt(dir, meta); err != nil {
+		return nil, toStatus(err)
+	}
+	delete(s.uploads, u.ID)
+	restoreLease := s.dropLease(u.Path) // 替换后的文件重新申请追加租约
+	err := s.persistOrUndo(func() {
+		if old != nil {
+			old.Metadata = previous
+		} else {
+			s.tree.RemoveAt(u.Path)
+		}
+		s.uploads[u.ID] = u
+		restoreLease()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// 放弃上传并回收已登记的分片
+func (s *metaServer) AbortUpload(ctx context.Context, req *metapb.UploadRequest) (*metapb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.lookupUpload(req.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	delete(s.uploads, u.ID)
+	if err := s.persistOrUndo(func() { s.uploads[u.ID] = u }); err != nil {
+		return nil, err
+	}
+	log.Printf("Aborted upload %s: %s", u.ID, u.Path)
+	go s.reclaim(u.ID, u.chunkList())
+	return &metapb.Empty{}, nil
+}
+
+// 列出未提交的上传和追加租约的分片，垃圾回收时这些分片视为仍被引用
+func (s *metaServer) ListUploads(ctx context.Context, req *metapb.Empty) (*metapb.ListUploadsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &metapb.ListUploadsResponse{}
+	for _, u := range s.uploads {
+		resp.Uploads = append(resp.Uploads, u.toProto())
+	}
+	sort.Slice(resp.Uploads, func(i, j int) bool { return resp.Uploads[i].Path < resp.Uploads[j].Path })
+	// 追加租约授予时其分片即视为被引用，避免垃圾回收删除尚未提交的记录
+	now := time.Now()
+	for _, lease := range s.leases {
+		if now.Before(lease.ExpiresAt) {
+			resp.AppendChunks = append(resp.AppendChunks, lease.Chunk.ToProto())
+		}
+	}
+	return resp, nil
+}
+
+// 元数据引用的全部分片：文件的分片、未提交上传（含未提交的分段）的分片和有效追加租约的分片
+// 在同一把锁下取得，分别查询时两次查询之间提交的上传会同时不在两者之中，其分片会被误当作孤儿
+func (s *metaServer) ReferencedChunks(ctx context.Context, req *metapb.Empty) (*metapb.ReferencedChunksResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	referenced := make(map[string]bool)
+	add := func(chunks []metadata.FileChunk) {
+		for _, chunk := range chunks {
+			if !chunk.IsHole() {
+				referenced[chunk.ChunkID] = true
+			}
+		}
+	}
+	s.tree.Walk(func(path string, meta *metadata.FileMetadata) {
+		add(meta.Chunks)
+	})
+	for _, u := range s.uploads {
+		add(u.chunkList())
+	}
+	now := time.Now()
+	for _, lease := range s.leases {
+		if now.Before(lease.ExpiresAt) {
+			add([]metadata.FileChunk{lease.Chunk})
+		}
+	}
+
+	resp := &metapb.ReferencedChunksResponse{ChunkIds: make([]string, 0, len(referenced))}
+	for chunkID := range referenced {
+		resp.ChunkIds = append(resp.ChunkIds, chunkID)
+	}
+	sort.Strings(resp.ChunkIds)
+	return resp, nil
+}
+
+// 定期清理过期的上传并回收其分片
+func (s *metaServer) startUploadExpiry(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			s.expireUploads()
+		}
+	}()
+}
+
+func (s *metaServer) expireUploads() {
+	var expired []*upload
+	now := time.Now()
+	s.mu.Lock()
+	for id, u := range s.uploads {
+		if now.After(u.ExpiresAt) {
+			expired = append(expired, u)
+			delete(s.uploads, id)
+		}
+	}
+	if len(expired) > 0 {
+		// 持久化失败时保留这些上传，下次清理时重试，避免回收快照中仍登记的分片
+		err := s.persistOrUndo(func() {
+			for _, u := range expired {
+				s.uploads[u.ID] = u
+			}
+		})
+		if err != nil {
+			expired = nil
+		}
+	}
+	s.mu.Unlock()
+
+	for _, u := range expired {
+		log.Printf("Upload %s to %s expired", u.ID, u.Path)
+		s.reclaim(u.ID, u.chunkList())
+	}
+}
+
+// 删除不再被引用的分片（放弃或过期的上传、被替换的分片），owner 用于日志
+// 节点地址未知或删除失败的副本留给垃圾回收
+func (s *metaServer) reclaim(owner string, chunks []metadata.FileChunk) {
+	var errs []error
+	deleted := 0
+	for _, chunk := range chunks {
+		for _, nodeID := range append(chunk.Locations(), chunk.Pending...) {
+			addr, exists := s.nodes.addr(nodeID)
+			if !exists {
+				errs = append(errs, fmt.Errorf("node %s: address unknown", nodeID))
+				continue
+			}
+			client, err := s.nodes.client(addr)
+			if err == nil {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				_, err = client.DeleteFile(ctx, &pb.DeleteRequest{Filename: chunk.ChunkID})
+				cancel()
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("node %s: %w", nodeID, err))
+				continue
+			}
+			deleted++
+		}
+	}
+	log.Printf("Reclaimed %d replica(s) of %s", deleted, owner)
+	if err := errors.Join(errs...); err != nil {
+		log.Printf("Some replicas of %s are left for garbage collection: %v", owner, err)
+	}
+}
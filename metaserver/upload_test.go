@@ -0,0 +1,444 @@
+package main
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+	metapb "grpc-distributed-fs/proto/meta"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 记录删除请求的存储节点
+type deleteRecorder struct {
+	pb.UnimplementedFileSystemServer
+	deleted chan string
+}
+
+func (n *deleteRecorder) DeleteFile(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	n.deleted <- req.Filename
+	return &pb.DeleteResponse{}, nil
+}
+
+// 以 node1 为 ID 启动记录删除请求的存储节点，并登记到元数据服务
+func startDeleteRecorder(t *testing.T, s *metaServer) *deleteRecorder {
+	t.Helper()
+	node := &deleteRecorder{deleted: make(chan string, 100)}
+	startStorageNode(t, s, "node1", node)
+	return node
+}
+
+// 在本地端口上启动以 nodeID 为 ID 的存储节点，并登记到元数据服务
+func startStorageNode(t *testing.T, s *metaServer, nodeID string, node pb.FileSystemServer) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterFileSystemServer(srv, node)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	s.nodes.update(nodeID, lis.Addr().String())
+}
+
+// 等待 n 个删除请求，再稍等片刻确认没有多余的请求，返回排序后的分片 ID
+func (n *deleteRecorder) wait(t *testing.T, count int) []string {
+	t.Helper()
+	var ids []string
+	timeout := time.After(5 * time.Second)
+	for len(ids) < count {
+		select {
+		case id := <-n.deleted:
+			ids = append(ids, id)
+		case <-timeout:
+			t.Fatalf("got %d of %d deletions: %v", len(ids), count, ids)
+		}
+	}
+	select {
+	case id := <-n.deleted:
+		ids = append(ids, id)
+	case <-time.After(100 * time.Millisecond):
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// 从编号 first 开始的 n 个新分片
+func newChunks(fileID string, first, n int) []metadata.FileChunk {
+	return testChunks(fileID, first+n, 10)[first:]
+}
+
+func TestCommitUpload(t *testing.T) {
+	tests := []struct {
+		name     string
+		written  []metadata.FileChunk
+		count    int
+		size     int64
+		change   func(t *testing.T, s *metaServer) // 开始上传之后的修改
+		wantCode codes.Code
+	}{
+		{
+			name:    "complete",
+			written: newChunks("new", 0, 2),
+			count:   2,
+			size:    20,
+		},
+		{
+			name:  "empty file",
+			count: 0,
+			size:  0,
+		},
+		{
+			name:     "chunk missing",
+			written:  newChunks("new", 1, 1),
+			count:    2,
+			size:     20,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "size mismatch",
+			written:  newChunks("new", 0, 2),
+			count:    2,
+			size:     25,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:    "file created meanwhile",
+			written: newChunks("new", 0, 1),
+			count:   1,
+			size:    10,
+			change: func(t *testing.T, s *metaServer) {
+				addTestFile(t, s, "/f", testChunks("other", 1, 10))
+			},
+			wantCode: codes.AlreadyExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			beginTestUpload(t, s, &metapb.BeginUploadRequest{Path: "/f", UploadId: "u"}, tt.written)
+			if tt.change != nil {
+				tt.change(t, s)
+			}
+
+			_, err := s.CommitUpload(context.Background(), &metapb.CommitUploadRequest{UploadId: "u", Chunks: int32(tt.count), Size: tt.size})
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Fatalf("CommitUpload = %v, want %v", err, tt.wantCode)
+				}
+				if s.uploads["u"] == nil {
+					t.Error("failed commit removed the upload")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, err := s.tree.Lookup("/f")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := chunkIDs(file.Metadata.Chunks), chunkIDs(tt.written); !slices.Equal(got, want) {
+				t.Errorf("chunks = %v, want %v", got, want)
+			}
+			if file.Metadata.Size != tt.size {
+				t.Errorf("size = %d, want %d", file.Metadata.Size, tt.size)
+			}
+			if s.uploads["u"] != nil {
+				t.Error("upload not removed")
+			}
+		})
+	}
+}
+
+// 过期的上传被移除并回收已写入的分片，未过期的保留
+func TestExpireUploads(t *testing.T) {
+	s := newTestServer(t)
+	node := startDeleteRecorder(t, s)
+	beginTestUpload(t, s, &metapb.BeginUploadRequest{Path: "/old", UploadId: "old"}, newChunks("old", 0, 2))
+	beginTestUpload(t, s, &metapb.BeginUploadRequest{Path: "/new", UploadId: "new"}, newChunks("new", 0, 1))
+	s.uploads["old"].ExpiresAt = time.Now().Add(-time.Second)
+
+	// 过期之后、回收之前同样不能提交
+	_, err := s.CommitUpload(context.Background(), &metapb.CommitUploadRequest{UploadId: "old", Chunks: 2, Size: 20})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("CommitUpload of expired upload = %v, want NotFound", err)
+	}
+	s.expireUploads()
+	if s.uploads["old"] != nil {
+		t.Error("expired upload kept")
+	}
+	if s.uploads["new"] == nil {
+		t.Error("live upload removed")
+	}
+	if got, want := node.wait(t, 2), []string{"old_0", "old_1"}; !slices.Equal(got, want) {
+		t.Errorf("reclaimed %v, want %v", got, want)
+	}
+	_, err = s.AddChunk(context.Background(), &metapb.AddChunkRequest{UploadId: "old", Chunk: newChunks("old", 2, 1)[0].ToProto()})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("AddChunk to expired upload = %v, want NotFound", err)
+	}
+}
+
+func TestCommitUploadSplice(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      []metadata.FileChunk // 原文件的分片，nil 表示文件不存在
+		keep     int
+		written  []metadata.FileChunk
+		count    int
+		change   func(s *metaServer) // 开始上传之后对原文件的修改
+		want     []string            // 提交后文件的分片
+		reclaim  []string            // 回收的分片
+		wantCode codes.Code
+	}{
+		{
+			name:    "new file",
+			written: newChunks("new", 0, 2),
+			count:   2,
+			want:    []string{"new_0", "new_1"},
+		},
+		{
+			name:    "overwrite",
+			old:     testChunks("old", 4, 10),
+			written: newChunks("new", 0, 2),
+			count:   2,
+			want:    []string{"new_0", "new_1"},
+			reclaim: []string{"old_0", "old_1", "old_2", "old_3"},
+		},
+		{
+			name:    "append",
+			old:     testChunks("old", 4, 10),
+			keep:    4,
+			written: newChunks("new", 4, 1),
+			count:   5,
+			want:    []string{"old_0", "old_1", "old_2", "old_3", "new_4"},
+		},
+		{
+			name:    "rewrite tail chunk",
+			old:     testChunks("old", 4, 10),
+			keep:    3,
+			written: newChunks("new", 3, 2),
+			count:   5,
+			want:    []string{"old_0", "old_1", "old_2", "new_3", "new_4"},
+			reclaim: []string{"old_3"},
+		},
+		{
+			name:    "replace middle chunk",
+			old:     testChunks("old", 4, 10),
+			keep:    1,
+			written: newChunks("new", 1, 1),
+			count:   4,
+			want:    []string{"old_0", "new_1", "old_2", "old_3"},
+			reclaim: []string{"old_1"},
+		},
+		{
+			name:    "shrink middle",
+			old:     testChunks("old", 4, 10),
+			keep:    1,
+			written: newChunks("new", 1, 1),
+			count:   3,
+			want:    []string{"old_0", "new_1", "old_3"},
+			reclaim: []string{"old_1", "old_2"},
+		},
+		{
+			name:    "grow middle",
+			old:     testChunks("old", 4, 10),
+			keep:    1,
+			written: newChunks("new", 1, 2),
+			count:   5,
+			want:    []string{"old_0", "new_1", "new_2", "old_2", "old_3"},
+			reclaim: []string{"old_1"},
+		},
+		{
+			name:    "insert without replacing",
+			old:     testChunks("old", 4, 10),
+			keep:    2,
+			written: newChunks("new", 2, 1),
+			count:   5,
+			want:    []string{"old_0", "old_1", "new_2", "old_2", "old_3"},
+		},
+		{
+			name: "holes and reused chunks are not reclaimed",
+			old: func() []metadata.FileChunk {
+				chunks := testChunks("old", 3, 10)
+				chunks[1] = metadata.FileChunk{ChunkNumber: 1, Size: 10}
+				return chunks
+			}(),
+			written: append(newChunks("new", 0, 1), func() metadata.FileChunk {
+				chunk := testChunks("old", 3, 10)[2]
+				chunk.ChunkNumber = 1
+				return chunk
+			}()),
+			count:   2,
+			want:    []string{"new_0", "old_2"},
+			reclaim: []string{"old_0"},
+		},
+		{
+			name:     "tail longer than file",
+			old:      testChunks("old", 4, 10),
+			keep:     3,
+			written:  newChunks("new", 3, 1),
+			count:    6,
+			wantCode: codes.Aborted,
+		},
+		{
+			name:     "chunk missing",
+			old:      testChunks("old", 4, 10),
+			keep:     4,
+			written:  newChunks("new", 5, 1),
+			count:    5,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:    "keep beyond truncated file",
+			old:     testChunks("old", 2, 10),
+			keep:    2,
+			written: newChunks("new", 2, 1),
+			count:   3,
+			change: func(s *metaServer) {
+				node, _ := s.tree.Lookup("/f")
+				node.Metadata.Chunks = node.Metadata.Chunks[:1]
+			},
+			wantCode: codes.Aborted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			node := startDeleteRecorder(t, s)
+			if tt.old != nil {
+				addTestFile(t, s, "/f", tt.old)
+			}
+			beginTestUpload(t, s, &metapb.BeginUploadRequest{Path: "/f", UploadId: "u", Overwrite: true, Keep: int32(tt.keep)}, tt.written)
+			if tt.change != nil {
+				tt.change(s)
+			}
+
+			// 所有分片大小均为 10
+			size := int64(tt.count) * 10
+			_, err := s.CommitUpload(context.Background(), &metapb.CommitUploadRequest{UploadId: "u", Chunks: int32(tt.count), Written: int32(len(tt.written)), Size: size})
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Fatalf("CommitUpload = %v, want %v", err, tt.wantCode)
+				}
+				if s.uploads["u"] == nil {
+					t.Error("failed commit removed the upload")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := s.tree.Lookup("/f")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chunkIDs(file.Metadata.Chunks); !slices.Equal(got, tt.want) {
+				t.Errorf("chunks = %v, want %v", got, tt.want)
+			}
+			for i, chunk := range file.Metadata.Chunks {
+				if chunk.ChunkNumber != i {
+					t.Errorf("chunk %s numbered %d at position %d", chunk.ChunkID, chunk.ChunkNumber, i)
+				}
+			}
+			if file.Metadata.Size != size {
+				t.Errorf("size = %d, want %d", file.Metadata.Size, size)
+			}
+			if s.uploads["u"] != nil {
+				t.Error("upload not removed")
+			}
+			if got := node.wait(t, len(tt.reclaim)); !slices.Equal(got, tt.reclaim) {
+				t.Errorf("reclaimed %v, want %v", got, tt.reclaim)
+			}
+		})
+	}
+}
+
+// 文件在上传期间被修改或删除时提交失败
+func TestCommitUploadConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, s *metaServer)
+	}{
+		{"modified", func(t *testing.T, s *metaServer) {
+			node, _ := s.tree.Lookup("/f")
+			node.Metadata.ModificationTime = node.Metadata.ModificationTime.Add(time.Second)
+		}},
+		{"removed", func(t *testing.T, s *metaServer) {
+			if err := s.tree.RemoveAt("/f"); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			meta := addTestFile(t, s, "/f", testChunks("old", 2, 10))
+			beginTestUpload(t, s, &metapb.BeginUploadRequest{
+				Path:                 "/f",
+				UploadId:             "u",
+				Overwrite:            true,
+				Keep:                 2,
+				BaseModificationTime: meta.ModificationTime.UnixNano(),
+			}, newChunks("new", 2, 1))
+			tt.change(t, s)
+			_, err := s.CommitUpload(context.Background(), &metapb.CommitUploadRequest{UploadId: "u", Chunks: 3, Size: 30})
+			if status.Code(err) != codes.Aborted {
+				t.Fatalf("CommitUpload = %v, want %v", err, codes.Aborted)
+			}
+		})
+	}
+}
+
+func TestUnreferenced(t *testing.T) {
+	hole := metadata.FileChunk{ChunkNumber: 1, Size: 10}
+	tests := []struct {
+		replaced []metadata.FileChunk
+		chunks   []metadata.FileChunk
+		want     []string
+	}{
+		{testChunks("a", 2, 10), nil, []string{"a_0", "a_1"}},
+		{testChunks("a", 2, 10), testChunks("a", 1, 10), []string{"a_1"}},
+		{testChunks("a", 2, 10), testChunks("a", 2, 10), nil},
+		{[]metadata.FileChunk{hole}, nil, nil},
+	}
+	for i, tt := range tests {
+		if got := chunkIDs(unreferenced(tt.replaced, tt.chunks)); !slices.Equal(got, tt.want) {
+			t.Errorf("%d: unreferenced = %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+// 文件、未提交的上传和分段、有效追加租约的分片都被引用；空洞和过期租约的分片不被引用
+func TestReferencedChunks(t *testing.T) {
+	s := newTestServer(t)
+	chunks := testChunks("f", 2, 10)
+	chunks = append(chunks, metadata.FileChunk{ChunkNumber: 2, Size: 10})
+	addTestFile(t, s, "/f", chunks)
+	beginTestUpload(t, s, &metapb.BeginUploadRequest{Path: "/u", UploadId: "u"}, newChunks("u", 0, 1))
+	beginTestUpload(t, s, &metapb.BeginUploadRequest{Path: "/m", UploadId: "m", Multipart: true}, nil)
+	part := testChunks("p", 1, 10)[0]
+	if _, err := s.AddChunk(context.Background(), &metapb.AddChunkRequest{UploadId: "m", PartId: "p", PartNumber: 1, Chunk: part.ToProto()}); err != nil {
+		t.Fatal(err)
+	}
+	s.leases["/r"] = &appendLease{Chunk: testChunks("live", 1, 0)[0], ExpiresAt: time.Now().Add(time.Minute)}
+	s.leases["/x"] = &appendLease{Chunk: testChunks("expired", 1, 0)[0], ExpiresAt: time.Now().Add(-time.Second)}
+
+	resp, err := s.ReferencedChunks(context.Background(), &metapb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"f_0", "f_1", "live_0", "p_0", "u_0"}; !slices.Equal(resp.ChunkIds, want) {
+		t.Errorf("referenced = %v, want %v", resp.ChunkIds, want)
+	}
+}
@@ -0,0 +1,87 @@
+package placement
+
+import "strings"
+
+// 故障域层级，由大到小
+var Levels = []string{"zone", "rack", "host"}
+
+// 故障域感知的放置策略：同一分片的副本分布在互不相同的故障域中
+type DomainAware struct {
+	ranker  *Rendezvous
+	domains map[string]string // 节点 ID -> 故障域
+}
+
+// 初始化故障域感知放置策略，level 为 zone、rack 或 host
+func NewDomainAware(nodes []Node, level string) *DomainAware {
+	domains := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		domains[node.ID] = domainOf(node, level)
+	}
+	return &DomainAware{ranker: NewRendezvous(nodes), domains: domains}
+}
+
+// 按 rendezvous 得分依次选取节点，跳过故障域已被占用的节点
+// 可用故障域不足 n 个时返回的节点数少于 n
+func (d *DomainAware) Place(chunkID string, n int) []string {
+	return d.PlaceMore(chunkID, nil, n)
+}
+
+// 为已有副本位于 existing 的分片补选 n 个节点，跳过与已有副本共用故障域的节点
+// 用于重新放置无法写入的副本，可用故障域不足时返回的节点数少于 n
+func (d *DomainAware) PlaceMore(chunkID string, existing []string, n int) []string {
+	var ids []string
+	used := make(map[string]bool)
+	for _, id := range existing {
+		used[d.Domain(id)] = true
+	}
+	for _, id := range d.ranker.Rank(chunkID) {
+		if len(ids) == n {
+			break
+		}
+		if used[d.domains[id]] {
+			continue
+		}
+		used[d.domains[id]] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// 节点所在故障域
+func (d *DomainAware) Domain(nodeID string) string {
+	if domain, exists := d.domains[nodeID]; exists {
+		return domain
+	}
+	return nodeID
+}
+
+// 返回共用故障域的节点，key 为故障域
+func (d *DomainAware) Conflicts(nodeIDs []string) map[string][]string {
+	byDomain := make(map[string][]string)
+	for _, id := range nodeIDs {
+		byDomain[d.Domain(id)] = append(byDomain[d.Domain(id)], id)
+	}
+	for domain, ids := range byDomain {
+		if len(ids) < 2 {
+			delete(byDomain, domain)
+		}
+	}
+	return byDomain
+}
+
+// 节点在指定层级上的故障域，形如 zone/rack/host
+// 缺少该层级标签的节点自成一个故障域
+func domainOf(node Node, level string) string {
+	var parts []string
+	for _, l := range Levels {
+		value := node.Labels[l]
+		if l == level && value == "" {
+			return "node:" + node.ID
+		}
+		parts = append(parts, value)
+		if l == level {
+			break
+		}
+	}
+	return strings.Join(parts, "/")
+}
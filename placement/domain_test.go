@@ -0,0 +1,124 @@
+package placement
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// 两个可用区、每个可用区两个机架、每个机架两台主机
+func testTopology() []Node {
+	var nodes []Node
+	for _, zone := range []string{"z1", "z2"} {
+		for _, rack := range []string{"r1", "r2"} {
+			for _, host := range []string{"h1", "h2"} {
+				id := fmt.Sprintf("%s-%s-%s", zone, rack, host)
+				nodes = append(nodes, Node{ID: id, Weight: 1, Labels: map[string]string{"zone": zone, "rack": zone + rack, "host": id}})
+			}
+		}
+	}
+	return nodes
+}
+
+func TestDomainAwarePlace(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []Node
+		level string
+		n     int
+		want  int // 返回的节点数
+	}{
+		{"hosts", testTopology(), "host", 3, 3},
+		{"racks", testTopology(), "rack", 3, 3},
+		{"racks exhausted", testTopology(), "rack", 5, 4},
+		{"zones exhausted", testTopology(), "zone", 3, 2},
+		{"unlabeled nodes are their own domain", []Node{{ID: "a", Weight: 1}, {ID: "b", Weight: 1}, {ID: "c", Weight: 1}}, "rack", 3, 3},
+		{"shared rack", []Node{
+			{ID: "a", Weight: 1, Labels: map[string]string{"rack": "r1"}},
+			{ID: "b", Weight: 1, Labels: map[string]string{"rack": "r1"}},
+			{ID: "c", Weight: 1, Labels: map[string]string{"rack": "r2"}},
+		}, "rack", 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDomainAware(tt.nodes, tt.level)
+			for i := range 200 {
+				ids := d.Place(fmt.Sprintf("chunk_%d", i), tt.n)
+				if len(ids) != tt.want {
+					t.Fatalf("Place = %v, want %d nodes", ids, tt.want)
+				}
+				if conflicts := d.Conflicts(ids); len(conflicts) > 0 {
+					t.Fatalf("Place = %v shares failure domains %v", ids, conflicts)
+				}
+			}
+		})
+	}
+}
+
+func TestDomainAwarePlaceMore(t *testing.T) {
+	d := NewDomainAware(testTopology(), "rack")
+	tests := []struct {
+		name     string
+		existing []string
+		n        int
+		want     int
+	}{
+		{"none existing", nil, 2, 2},
+		{"one existing", []string{"z1-r1-h1"}, 2, 2},
+		{"racks used up", []string{"z1-r1-h1", "z1-r2-h1", "z2-r1-h1"}, 2, 1},
+		{"existing node outside the cluster", []string{"gone"}, 4, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range 200 {
+				ids := d.PlaceMore(fmt.Sprintf("chunk_%d", i), tt.existing, tt.n)
+				if len(ids) != tt.want {
+					t.Fatalf("PlaceMore = %v, want %d nodes", ids, tt.want)
+				}
+				all := append(slices.Clone(tt.existing), ids...)
+				if conflicts := d.Conflicts(all); len(conflicts) > 0 {
+					t.Fatalf("PlaceMore = %v shares failure domains with %v: %v", ids, tt.existing, conflicts)
+				}
+			}
+		})
+	}
+}
+
+func TestDomainOf(t *testing.T) {
+	node := Node{ID: "n", Labels: map[string]string{"zone": "z", "rack": "r", "host": "h"}}
+	tests := []struct {
+		node  Node
+		level string
+		want  string
+	}{
+		{node, "zone", "z"},
+		{node, "rack", "z/r"},
+		{node, "host", "z/r/h"},
+		{Node{ID: "n", Labels: map[string]string{"host": "h"}}, "host", "//h"},
+		{Node{ID: "n", Labels: map[string]string{"zone": "z"}}, "rack", "node:n"},
+		{Node{ID: "n"}, "zone", "node:n"},
+	}
+	for _, tt := range tests {
+		if got := domainOf(tt.node, tt.level); got != tt.want {
+			t.Errorf("domainOf(%v, %s) = %q, want %q", tt.node.Labels, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	d := NewDomainAware(testTopology(), "rack")
+	tests := []struct {
+		ids  []string
+		want map[string][]string
+	}{
+		{[]string{"z1-r1-h1", "z1-r2-h1"}, map[string][]string{}},
+		{[]string{"z1-r1-h1", "z1-r1-h2", "z2-r1-h1"}, map[string][]string{"z1/z1r1": {"z1-r1-h1", "z1-r1-h2"}}},
+		{[]string{"x", "x"}, map[string][]string{"x": {"x", "x"}}},
+	}
+	for _, tt := range tests {
+		got := d.Conflicts(tt.ids)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("Conflicts(%v) = %v, want %v", tt.ids, got, tt.want)
+		}
+	}
+}
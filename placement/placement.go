@@ -0,0 +1,86 @@
+package placement
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// 存储节点
+type Node struct {
+	ID     string            // 节点唯一标识符
+	Weight float64           // 节点权重，通常取节点容量
+	Labels map[string]string // 节点拓扑标签（host、rack、zone）
+}
+
+// 分片放置策略：根据分片 ID 选出 n 个互不相同的存储节点
+type Strategy interface {
+	Place(chunkID string, n int) []string
+}
+
+// 加权 rendezvous 哈希（HRW）
+// 节点增减时只有落在该节点上的分片需要迁移，其余分片位置保持不变
+type Rendezvous struct {
+	nodes []Node
+}
+
+// 初始化 rendezvous 放置策略
+func NewRendezvous(nodes []Node) *Rendezvous {
+	return &Rendezvous{nodes: nodes}
+}
+
+// 按得分从高到低返回前 n 个节点 ID
+func (r *Rendezvous) Place(chunkID string, n int) []string {
+	ids := r.Rank(chunkID)
+	if n < len(ids) {
+		ids = ids[:n]
+	}
+	return ids
+}
+
+// 按得分从高到低返回全部节点 ID
+func (r *Rendezvous) Rank(chunkID string) []string {
+	type scored struct {
+		id    string
+		score float64
+	}
+	scores := make([]scored, 0, len(r.nodes))
+	for _, node := range r.nodes {
+		if node.Weight <= 0 {
+			continue
+		}
+		scores = append(scores, scored{node.ID, score(node, chunkID)})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].score == scores[j].score {
+			return scores[i].id < scores[j].id
+		}
+		return scores[i].score > scores[j].score
+	})
+
+	ids := make([]string, len(scores))
+	for i, s := range scores {
+		ids[i] = s.id
+	}
+	return ids
+}
+
+// 加权得分：-w / ln(u)，u 为 (节点, 分片) 哈希映射到 (0, 1) 的值
+func score(node Node, chunkID string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(node.ID))
+	h.Write([]byte{0})
+	h.Write([]byte(chunkID))
+	u := (float64(mix(h.Sum64())>>11) + 0.5) / (1 << 53)
+	return -node.Weight / math.Log(u)
+}
+
+// splitmix64 终结函数，打散 FNV 高位分布不均的问题
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
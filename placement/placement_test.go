@@ -0,0 +1,90 @@
+package placement
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+)
+
+// 大量分片的主副本按权重比例分布在节点上
+func TestRendezvousWeights(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []Node
+	}{
+		{"equal", []Node{{ID: "a", Weight: 1}, {ID: "b", Weight: 1}, {ID: "c", Weight: 1}}},
+		{"proportional", []Node{{ID: "a", Weight: 1}, {ID: "b", Weight: 2}, {ID: "c", Weight: 3}}},
+		{"zero weight excluded", []Node{{ID: "a", Weight: 1}, {ID: "b", Weight: 0}, {ID: "c", Weight: 1}}},
+		{"large spread", []Node{{ID: "a", Weight: 10}, {ID: "b", Weight: 1}}},
+	}
+	const chunks = 30000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRendezvous(tt.nodes)
+			counts := make(map[string]int)
+			for i := range chunks {
+				counts[r.Place(fmt.Sprintf("file_%d", i), 1)[0]]++
+			}
+			var total float64
+			for _, node := range tt.nodes {
+				total += node.Weight
+			}
+			for _, node := range tt.nodes {
+				want := chunks * node.Weight / total
+				if got := float64(counts[node.ID]); math.Abs(got-want) > 0.05*chunks {
+					t.Errorf("node %s got %.0f chunks, want about %.0f", node.ID, got, want)
+				}
+			}
+		})
+	}
+}
+
+// 移除节点时只有落在该节点上的分片改变位置，增加节点时只有移到新节点的分片改变位置
+func TestRendezvousMinimalMovement(t *testing.T) {
+	before := []Node{{ID: "a", Weight: 1}, {ID: "b", Weight: 2}, {ID: "c", Weight: 1}}
+	tests := []struct {
+		name  string
+		after []Node
+	}{
+		{"remove", []Node{{ID: "a", Weight: 1}, {ID: "c", Weight: 1}}},
+		{"add", append(slices.Clone(before), Node{ID: "d", Weight: 2})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r1, r2 := NewRendezvous(before), NewRendezvous(tt.after)
+			for i := range 2000 {
+				chunkID := fmt.Sprintf("file_%d", i)
+				from, to := r1.Place(chunkID, 1)[0], r2.Place(chunkID, 1)[0]
+				if from == to {
+					continue
+				}
+				if slices.ContainsFunc(tt.after, func(n Node) bool { return n.ID == from }) && to != "d" {
+					t.Fatalf("%s moved from %s to %s", chunkID, from, to)
+				}
+			}
+		})
+	}
+}
+
+func TestRendezvousPlace(t *testing.T) {
+	r := NewRendezvous([]Node{{ID: "a", Weight: 1}, {ID: "b", Weight: 1}, {ID: "c", Weight: 1}})
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{0, 0},
+		{1, 1},
+		{3, 3},
+		{5, 3},
+	}
+	for _, tt := range tests {
+		ids := r.Place("chunk", tt.n)
+		if len(ids) != tt.want {
+			t.Errorf("Place(%d) = %v, want %d nodes", tt.n, ids, tt.want)
+		}
+		if !slices.Equal(ids, r.Rank("chunk")[:len(ids)]) {
+			t.Errorf("Place(%d) = %v, not a prefix of the ranking", tt.n, ids)
+		}
+	}
+}
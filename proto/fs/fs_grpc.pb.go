@@ -19,20 +19,35 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	FileSystem_WriteFile_FullMethodName  = "/fs.FileSystem/WriteFile"
-	FileSystem_ReadFile_FullMethodName   = "/fs.FileSystem/ReadFile"
-	FileSystem_DeleteFile_FullMethodName = "/fs.FileSystem/DeleteFile"
-	FileSystem_ListFiles_FullMethodName  = "/fs.FileSystem/ListFiles"
+	FileSystem_WriteFile_FullMethodName     = "/fs.FileSystem/WriteFile"
+	FileSystem_ReadFile_FullMethodName      = "/fs.FileSystem/ReadFile"
+	FileSystem_DeleteFile_FullMethodName    = "/fs.FileSystem/DeleteFile"
+	FileSystem_ListFiles_FullMethodName     = "/fs.FileSystem/ListFiles"
+	FileSystem_StatFile_FullMethodName      = "/fs.FileSystem/StatFile"
+	FileSystem_NodeInfo_FullMethodName      = "/fs.FileSystem/NodeInfo"
+	FileSystem_PipelineWrite_FullMethodName = "/fs.FileSystem/PipelineWrite"
+	FileSystem_Replicate_FullMethodName     = "/fs.FileSystem/Replicate"
+	FileSystem_AppendRecord_FullMethodName  = "/fs.FileSystem/AppendRecord"
+	FileSystem_WriteRecord_FullMethodName   = "/fs.FileSystem/WriteRecord"
 )
 
 // FileSystemClient is the client API for FileSystem service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// 服务中的方法
 type FileSystemClient interface {
 	WriteFile(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*WriteResponse, error)
 	ReadFile(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (*ReadResponse, error)
 	DeleteFile(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
 	ListFiles(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	StatFile(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error)
+	NodeInfo(ctx context.Context, in *NodeInfoRequest, opts ...grpc.CallOption) (*NodeInfoResponse, error)
+	PipelineWrite(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PipelineWriteRequest, PipelineWriteResponse], error)
+	Replicate(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (*ReplicateResponse, error)
+	// 记录追加：由持有租约的主副本决定偏移，再写入其余副本
+	AppendRecord(ctx context.Context, in *AppendRecordRequest, opts ...grpc.CallOption) (*AppendRecordResponse, error)
+	WriteRecord(ctx context.Context, in *WriteRecordRequest, opts ...grpc.CallOption) (*WriteResponse, error)
 }
 
 type fileSystemClient struct {
@@ -83,14 +98,86 @@ func (c *fileSystemClient) ListFiles(ctx context.Context, in *ListRequest, opts
 	return out, nil
 }
 
+func (c *fileSystemClient) StatFile(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatResponse)
+	err := c.cc.Invoke(ctx, FileSystem_StatFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileSystemClient) NodeInfo(ctx context.Context, in *NodeInfoRequest, opts ...grpc.CallOption) (*NodeInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NodeInfoResponse)
+	err := c.cc.Invoke(ctx, FileSystem_NodeInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileSystemClient) PipelineWrite(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PipelineWriteRequest, PipelineWriteResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FileSystem_ServiceDesc.Streams[0], FileSystem_PipelineWrite_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PipelineWriteRequest, PipelineWriteResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileSystem_PipelineWriteClient = grpc.ClientStreamingClient[PipelineWriteRequest, PipelineWriteResponse]
+
+func (c *fileSystemClient) Replicate(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (*ReplicateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReplicateResponse)
+	err := c.cc.Invoke(ctx, FileSystem_Replicate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileSystemClient) AppendRecord(ctx context.Context, in *AppendRecordRequest, opts ...grpc.CallOption) (*AppendRecordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AppendRecordResponse)
+	err := c.cc.Invoke(ctx, FileSystem_AppendRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileSystemClient) WriteRecord(ctx context.Context, in *WriteRecordRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, FileSystem_WriteRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FileSystemServer is the server API for FileSystem service.
 // All implementations must embed UnimplementedFileSystemServer
 // for forward compatibility.
+//
+// 服务中的方法
 type FileSystemServer interface {
 	WriteFile(context.Context, *WriteRequest) (*WriteResponse, error)
 	ReadFile(context.Context, *ReadRequest) (*ReadResponse, error)
 	DeleteFile(context.Context, *DeleteRequest) (*DeleteResponse, error)
 	ListFiles(context.Context, *ListRequest) (*ListResponse, error)
+	StatFile(context.Context, *StatRequest) (*StatResponse, error)
+	NodeInfo(context.Context, *NodeInfoRequest) (*NodeInfoResponse, error)
+	PipelineWrite(grpc.ClientStreamingServer[PipelineWriteRequest, PipelineWriteResponse]) error
+	Replicate(context.Context, *ReplicateRequest) (*ReplicateResponse, error)
+	// 记录追加：由持有租约的主副本决定偏移，再写入其余副本
+	AppendRecord(context.Context, *AppendRecordRequest) (*AppendRecordResponse, error)
+	WriteRecord(context.Context, *WriteRecordRequest) (*WriteResponse, error)
 	mustEmbedUnimplementedFileSystemServer()
 }
 
@@ -113,6 +200,24 @@ func (UnimplementedFileSystemServer) DeleteFile(context.Context, *DeleteRequest)
 func (UnimplementedFileSystemServer) ListFiles(context.Context, *ListRequest) (*ListResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListFiles not implemented")
 }
+func (UnimplementedFileSystemServer) StatFile(context.Context, *StatRequest) (*StatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StatFile not implemented")
+}
+func (UnimplementedFileSystemServer) NodeInfo(context.Context, *NodeInfoRequest) (*NodeInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NodeInfo not implemented")
+}
+func (UnimplementedFileSystemServer) PipelineWrite(grpc.ClientStreamingServer[PipelineWriteRequest, PipelineWriteResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method PipelineWrite not implemented")
+}
+func (UnimplementedFileSystemServer) Replicate(context.Context, *ReplicateRequest) (*ReplicateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Replicate not implemented")
+}
+func (UnimplementedFileSystemServer) AppendRecord(context.Context, *AppendRecordRequest) (*AppendRecordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AppendRecord not implemented")
+}
+func (UnimplementedFileSystemServer) WriteRecord(context.Context, *WriteRecordRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WriteRecord not implemented")
+}
 func (UnimplementedFileSystemServer) mustEmbedUnimplementedFileSystemServer() {}
 func (UnimplementedFileSystemServer) testEmbeddedByValue()                    {}
 
@@ -206,6 +311,103 @@ func _FileSystem_ListFiles_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FileSystem_StatFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileSystemServer).StatFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileSystem_StatFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileSystemServer).StatFile(ctx, req.(*StatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileSystem_NodeInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileSystemServer).NodeInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileSystem_NodeInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileSystemServer).NodeInfo(ctx, req.(*NodeInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileSystem_PipelineWrite_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FileSystemServer).PipelineWrite(&grpc.GenericServerStream[PipelineWriteRequest, PipelineWriteResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileSystem_PipelineWriteServer = grpc.ClientStreamingServer[PipelineWriteRequest, PipelineWriteResponse]
+
+func _FileSystem_Replicate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplicateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileSystemServer).Replicate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileSystem_Replicate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileSystemServer).Replicate(ctx, req.(*ReplicateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileSystem_AppendRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileSystemServer).AppendRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileSystem_AppendRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileSystemServer).AppendRecord(ctx, req.(*AppendRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileSystem_WriteRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileSystemServer).WriteRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileSystem_WriteRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileSystemServer).WriteRecord(ctx, req.(*WriteRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // FileSystem_ServiceDesc is the grpc.ServiceDesc for FileSystem service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -229,7 +431,33 @@ var FileSystem_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListFiles",
 			Handler:    _FileSystem_ListFiles_Handler,
 		},
+		{
+			MethodName: "StatFile",
+			Handler:    _FileSystem_StatFile_Handler,
+		},
+		{
+			MethodName: "NodeInfo",
+			Handler:    _FileSystem_NodeInfo_Handler,
+		},
+		{
+			MethodName: "Replicate",
+			Handler:    _FileSystem_Replicate_Handler,
+		},
+		{
+			MethodName: "AppendRecord",
+			Handler:    _FileSystem_AppendRecord_Handler,
+		},
+		{
+			MethodName: "WriteRecord",
+			Handler:    _FileSystem_WriteRecord_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PipelineWrite",
+			Handler:       _FileSystem_PipelineWrite_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/fs.proto",
 }
@@ -0,0 +1,1013 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.21.12
+// source: proto/meta.proto
+
+package meta
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Metadata_Mkdir_FullMethodName                   = "/meta.Metadata/Mkdir"
+	Metadata_List_FullMethodName                    = "/meta.Metadata/List"
+	Metadata_Stat_FullMethodName                    = "/meta.Metadata/Stat"
+	Metadata_AddFile_FullMethodName                 = "/meta.Metadata/AddFile"
+	Metadata_RemoveFile_FullMethodName              = "/meta.Metadata/RemoveFile"
+	Metadata_Rename_FullMethodName                  = "/meta.Metadata/Rename"
+	Metadata_Walk_FullMethodName                    = "/meta.Metadata/Walk"
+	Metadata_BlockReport_FullMethodName             = "/meta.Metadata/BlockReport"
+	Metadata_MissingReplicas_FullMethodName         = "/meta.Metadata/MissingReplicas"
+	Metadata_GetDefaults_FullMethodName             = "/meta.Metadata/GetDefaults"
+	Metadata_SetDefaults_FullMethodName             = "/meta.Metadata/SetDefaults"
+	Metadata_BeginUpload_FullMethodName             = "/meta.Metadata/BeginUpload"
+	Metadata_AddChunk_FullMethodName                = "/meta.Metadata/AddChunk"
+	Metadata_CommitUpload_FullMethodName            = "/meta.Metadata/CommitUpload"
+	Metadata_AbortUpload_FullMethodName             = "/meta.Metadata/AbortUpload"
+	Metadata_ListUploads_FullMethodName             = "/meta.Metadata/ListUploads"
+	Metadata_ReferencedChunks_FullMethodName        = "/meta.Metadata/ReferencedChunks"
+	Metadata_GetUpload_FullMethodName               = "/meta.Metadata/GetUpload"
+	Metadata_CommitPart_FullMethodName              = "/meta.Metadata/CommitPart"
+	Metadata_CompleteMultipartUpload_FullMethodName = "/meta.Metadata/CompleteMultipartUpload"
+	Metadata_GrantAppendLease_FullMethodName        = "/meta.Metadata/GrantAppendLease"
+	Metadata_CommitRecord_FullMethodName            = "/meta.Metadata/CommitRecord"
+	Metadata_Lock_FullMethodName                    = "/meta.Metadata/Lock"
+	Metadata_Unlock_FullMethodName                  = "/meta.Metadata/Unlock"
+)
+
+// MetadataClient is the client API for Metadata service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// 元数据服务：维护目录树、分片位置，并接收存储节点的块报告
+type MetadataClient interface {
+	Mkdir(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*Empty, error)
+	List(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	Stat(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*FileMetadata, error)
+	AddFile(ctx context.Context, in *AddFileRequest, opts ...grpc.CallOption) (*Empty, error)
+	RemoveFile(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*Empty, error)
+	Rename(ctx context.Context, in *RenameRequest, opts ...grpc.CallOption) (*Empty, error)
+	Walk(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WalkEntry], error)
+	BlockReport(ctx context.Context, in *BlockReportRequest, opts ...grpc.CallOption) (*BlockReportResponse, error)
+	MissingReplicas(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*MissingReplicasResponse, error)
+	GetDefaults(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*Defaults, error)
+	SetDefaults(ctx context.Context, in *SetDefaultsRequest, opts ...grpc.CallOption) (*Empty, error)
+	// 两阶段上传：分片写入后逐个登记，全部完成后提交，提交前对读者不可见
+	BeginUpload(ctx context.Context, in *BeginUploadRequest, opts ...grpc.CallOption) (*UploadInfo, error)
+	AddChunk(ctx context.Context, in *AddChunkRequest, opts ...grpc.CallOption) (*UploadInfo, error)
+	CommitUpload(ctx context.Context, in *CommitUploadRequest, opts ...grpc.CallOption) (*FileMetadata, error)
+	AbortUpload(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*Empty, error)
+	ListUploads(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListUploadsResponse, error)
+	// 垃圾回收：文件、未提交的上传和有效追加租约引用的全部分片，在同一把锁下取得
+	ReferencedChunks(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ReferencedChunksResponse, error)
+	GetUpload(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*UploadInfo, error)
+	// 分段上传：各分段独立写入并提交，可以来自不同客户端、顺序任意，完成时按分段编号拼接分片列表，不复制数据
+	CommitPart(ctx context.Context, in *CommitPartRequest, opts ...grpc.CallOption) (*PartInfo, error)
+	CompleteMultipartUpload(ctx context.Context, in *CompleteMultipartRequest, opts ...grpc.CallOption) (*FileMetadata, error)
+	// 记录追加：为文件授予追加租约，指定当前可追加的分片及其主副本；记录写入后提交分片的新大小
+	GrantAppendLease(ctx context.Context, in *AppendLeaseRequest, opts ...grpc.CallOption) (*AppendLease, error)
+	CommitRecord(ctx context.Context, in *CommitRecordRequest, opts ...grpc.CallOption) (*CommitRecordResponse, error)
+	// 路径租约：持有者由请求的 gRPC 元数据 dfs-lock-owner 指定，其他客户端修改被锁定的路径时返回 FailedPrecondition
+	Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (*LockInfo, error)
+	Unlock(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type metadataClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMetadataClient(cc grpc.ClientConnInterface) MetadataClient {
+	return &metadataClient{cc}
+}
+
+func (c *metadataClient) Mkdir(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Metadata_Mkdir_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) List(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, Metadata_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) Stat(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*FileMetadata, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileMetadata)
+	err := c.cc.Invoke(ctx, Metadata_Stat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) AddFile(ctx context.Context, in *AddFileRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Metadata_AddFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) RemoveFile(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Metadata_RemoveFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) Rename(ctx context.Context, in *RenameRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Metadata_Rename_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) Walk(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WalkEntry], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Metadata_ServiceDesc.Streams[0], Metadata_Walk_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PathRequest, WalkEntry]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Metadata_WalkClient = grpc.ServerStreamingClient[WalkEntry]
+
+func (c *metadataClient) BlockReport(ctx context.Context, in *BlockReportRequest, opts ...grpc.CallOption) (*BlockReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BlockReportResponse)
+	err := c.cc.Invoke(ctx, Metadata_BlockReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) MissingReplicas(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*MissingReplicasResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MissingReplicasResponse)
+	err := c.cc.Invoke(ctx, Metadata_MissingReplicas_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) GetDefaults(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*Defaults, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Defaults)
+	err := c.cc.Invoke(ctx, Metadata_GetDefaults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) SetDefaults(ctx context.Context, in *SetDefaultsRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Metadata_SetDefaults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) BeginUpload(ctx context.Context, in *BeginUploadRequest, opts ...grpc.CallOption) (*UploadInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UploadInfo)
+	err := c.cc.Invoke(ctx, Metadata_BeginUpload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) AddChunk(ctx context.Context, in *AddChunkRequest, opts ...grpc.CallOption) (*UploadInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UploadInfo)
+	err := c.cc.Invoke(ctx, Metadata_AddChunk_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) CommitUpload(ctx context.Context, in *CommitUploadRequest, opts ...grpc.CallOption) (*FileMetadata, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileMetadata)
+	err := c.cc.Invoke(ctx, Metadata_CommitUpload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) AbortUpload(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Metadata_AbortUpload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) ListUploads(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListUploadsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUploadsResponse)
+	err := c.cc.Invoke(ctx, Metadata_ListUploads_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) ReferencedChunks(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ReferencedChunksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReferencedChunksResponse)
+	err := c.cc.Invoke(ctx, Metadata_ReferencedChunks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) GetUpload(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*UploadInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UploadInfo)
+	err := c.cc.Invoke(ctx, Metadata_GetUpload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) CommitPart(ctx context.Context, in *CommitPartRequest, opts ...grpc.CallOption) (*PartInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PartInfo)
+	err := c.cc.Invoke(ctx, Metadata_CommitPart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) CompleteMultipartUpload(ctx context.Context, in *CompleteMultipartRequest, opts ...grpc.CallOption) (*FileMetadata, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileMetadata)
+	err := c.cc.Invoke(ctx, Metadata_CompleteMultipartUpload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) GrantAppendLease(ctx context.Context, in *AppendLeaseRequest, opts ...grpc.CallOption) (*AppendLease, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AppendLease)
+	err := c.cc.Invoke(ctx, Metadata_GrantAppendLease_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) CommitRecord(ctx context.Context, in *CommitRecordRequest, opts ...grpc.CallOption) (*CommitRecordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommitRecordResponse)
+	err := c.cc.Invoke(ctx, Metadata_CommitRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (*LockInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LockInfo)
+	err := c.cc.Invoke(ctx, Metadata_Lock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataClient) Unlock(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Metadata_Unlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MetadataServer is the server API for Metadata service.
+// All implementations must embed UnimplementedMetadataServer
+// for forward compatibility.
+//
+// 元数据服务：维护目录树、分片位置，并接收存储节点的块报告
+type MetadataServer interface {
+	Mkdir(context.Context, *PathRequest) (*Empty, error)
+	List(context.Context, *PathRequest) (*ListResponse, error)
+	Stat(context.Context, *PathRequest) (*FileMetadata, error)
+	AddFile(context.Context, *AddFileRequest) (*Empty, error)
+	RemoveFile(context.Context, *PathRequest) (*Empty, error)
+	Rename(context.Context, *RenameRequest) (*Empty, error)
+	Walk(*PathRequest, grpc.ServerStreamingServer[WalkEntry]) error
+	BlockReport(context.Context, *BlockReportRequest) (*BlockReportResponse, error)
+	MissingReplicas(context.Context, *Empty) (*MissingReplicasResponse, error)
+	GetDefaults(context.Context, *PathRequest) (*Defaults, error)
+	SetDefaults(context.Context, *SetDefaultsRequest) (*Empty, error)
+	// 两阶段上传：分片写入后逐个登记，全部完成后提交，提交前对读者不可见
+	BeginUpload(context.Context, *BeginUploadRequest) (*UploadInfo, error)
+	AddChunk(context.Context, *AddChunkRequest) (*UploadInfo, error)
+	CommitUpload(context.Context, *CommitUploadRequest) (*FileMetadata, error)
+	AbortUpload(context.Context, *UploadRequest) (*Empty, error)
+	ListUploads(context.Context, *Empty) (*ListUploadsResponse, error)
+	// 垃圾回收：文件、未提交的上传和有效追加租约引用的全部分片，在同一把锁下取得
+	ReferencedChunks(context.Context, *Empty) (*ReferencedChunksResponse, error)
+	GetUpload(context.Context, *UploadRequest) (*UploadInfo, error)
+	// 分段上传：各分段独立写入并提交，可以来自不同客户端、顺序任意，完成时按分段编号拼接分片列表，不复制数据
+	CommitPart(context.Context, *CommitPartRequest) (*PartInfo, error)
+	CompleteMultipartUpload(context.Context, *CompleteMultipartRequest) (*FileMetadata, error)
+	// 记录追加：为文件授予追加租约，指定当前可追加的分片及其主副本；记录写入后提交分片的新大小
+	GrantAppendLease(context.Context, *AppendLeaseRequest) (*AppendLease, error)
+	CommitRecord(context.Context, *CommitRecordRequest) (*CommitRecordResponse, error)
+	// 路径租约：持有者由请求的 gRPC 元数据 dfs-lock-owner 指定，其他客户端修改被锁定的路径时返回 FailedPrecondition
+	Lock(context.Context, *LockRequest) (*LockInfo, error)
+	Unlock(context.Context, *PathRequest) (*Empty, error)
+	mustEmbedUnimplementedMetadataServer()
+}
+
+// UnimplementedMetadataServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMetadataServer struct{}
+
+func (UnimplementedMetadataServer) Mkdir(context.Context, *PathRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Mkdir not implemented")
+}
+func (UnimplementedMetadataServer) List(context.Context, *PathRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedMetadataServer) Stat(context.Context, *PathRequest) (*FileMetadata, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stat not implemented")
+}
+func (UnimplementedMetadataServer) AddFile(context.Context, *AddFileRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddFile not implemented")
+}
+func (UnimplementedMetadataServer) RemoveFile(context.Context, *PathRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveFile not implemented")
+}
+func (UnimplementedMetadataServer) Rename(context.Context, *RenameRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rename not implemented")
+}
+func (UnimplementedMetadataServer) Walk(*PathRequest, grpc.ServerStreamingServer[WalkEntry]) error {
+	return status.Errorf(codes.Unimplemented, "method Walk not implemented")
+}
+func (UnimplementedMetadataServer) BlockReport(context.Context, *BlockReportRequest) (*BlockReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BlockReport not implemented")
+}
+func (UnimplementedMetadataServer) MissingReplicas(context.Context, *Empty) (*MissingReplicasResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MissingReplicas not implemented")
+}
+func (UnimplementedMetadataServer) GetDefaults(context.Context, *PathRequest) (*Defaults, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDefaults not implemented")
+}
+func (UnimplementedMetadataServer) SetDefaults(context.Context, *SetDefaultsRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDefaults not implemented")
+}
+func (UnimplementedMetadataServer) BeginUpload(context.Context, *BeginUploadRequest) (*UploadInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeginUpload not implemented")
+}
+func (UnimplementedMetadataServer) AddChunk(context.Context, *AddChunkRequest) (*UploadInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddChunk not implemented")
+}
+func (UnimplementedMetadataServer) CommitUpload(context.Context, *CommitUploadRequest) (*FileMetadata, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitUpload not implemented")
+}
+func (UnimplementedMetadataServer) AbortUpload(context.Context, *UploadRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AbortUpload not implemented")
+}
+func (UnimplementedMetadataServer) ListUploads(context.Context, *Empty) (*ListUploadsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUploads not implemented")
+}
+func (UnimplementedMetadataServer) ReferencedChunks(context.Context, *Empty) (*ReferencedChunksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReferencedChunks not implemented")
+}
+func (UnimplementedMetadataServer) GetUpload(context.Context, *UploadRequest) (*UploadInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUpload not implemented")
+}
+func (UnimplementedMetadataServer) CommitPart(context.Context, *CommitPartRequest) (*PartInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitPart not implemented")
+}
+func (UnimplementedMetadataServer) CompleteMultipartUpload(context.Context, *CompleteMultipartRequest) (*FileMetadata, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompleteMultipartUpload not implemented")
+}
+func (UnimplementedMetadataServer) GrantAppendLease(context.Context, *AppendLeaseRequest) (*AppendLease, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GrantAppendLease not implemented")
+}
+func (UnimplementedMetadataServer) CommitRecord(context.Context, *CommitRecordRequest) (*CommitRecordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitRecord not implemented")
+}
+func (UnimplementedMetadataServer) Lock(context.Context, *LockRequest) (*LockInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lock not implemented")
+}
+func (UnimplementedMetadataServer) Unlock(context.Context, *PathRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unlock not implemented")
+}
+func (UnimplementedMetadataServer) mustEmbedUnimplementedMetadataServer() {}
+func (UnimplementedMetadataServer) testEmbeddedByValue()                  {}
+
+// UnsafeMetadataServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MetadataServer will
+// result in compilation errors.
+type UnsafeMetadataServer interface {
+	mustEmbedUnimplementedMetadataServer()
+}
+
+func RegisterMetadataServer(s grpc.ServiceRegistrar, srv MetadataServer) {
+	// If the following call pancis, it indicates UnimplementedMetadataServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Metadata_ServiceDesc, srv)
+}
+
+func _Metadata_Mkdir_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).Mkdir(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_Mkdir_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).Mkdir(ctx, req.(*PathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).List(ctx, req.(*PathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_Stat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).Stat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_Stat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).Stat(ctx, req.(*PathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_AddFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).AddFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_AddFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).AddFile(ctx, req.(*AddFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_RemoveFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).RemoveFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_RemoveFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).RemoveFile(ctx, req.(*PathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_Rename_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).Rename(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_Rename_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).Rename(ctx, req.(*RenameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_Walk_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PathRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MetadataServer).Walk(m, &grpc.GenericServerStream[PathRequest, WalkEntry]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Metadata_WalkServer = grpc.ServerStreamingServer[WalkEntry]
+
+func _Metadata_BlockReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).BlockReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_BlockReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).BlockReport(ctx, req.(*BlockReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_MissingReplicas_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).MissingReplicas(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_MissingReplicas_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).MissingReplicas(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_GetDefaults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).GetDefaults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_GetDefaults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).GetDefaults(ctx, req.(*PathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_SetDefaults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDefaultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).SetDefaults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_SetDefaults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).SetDefaults(ctx, req.(*SetDefaultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_BeginUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeginUploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).BeginUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_BeginUpload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).BeginUpload(ctx, req.(*BeginUploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_AddChunk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddChunkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).AddChunk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_AddChunk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).AddChunk(ctx, req.(*AddChunkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_CommitUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitUploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).CommitUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_CommitUpload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).CommitUpload(ctx, req.(*CommitUploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_AbortUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).AbortUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_AbortUpload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).AbortUpload(ctx, req.(*UploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_ListUploads_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).ListUploads(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_ListUploads_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).ListUploads(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_ReferencedChunks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).ReferencedChunks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_ReferencedChunks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).ReferencedChunks(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_GetUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).GetUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_GetUpload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).GetUpload(ctx, req.(*UploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_CommitPart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitPartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).CommitPart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_CommitPart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).CommitPart(ctx, req.(*CommitPartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_CompleteMultipartUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompleteMultipartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).CompleteMultipartUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_CompleteMultipartUpload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).CompleteMultipartUpload(ctx, req.(*CompleteMultipartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_GrantAppendLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).GrantAppendLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_GrantAppendLease_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).GrantAppendLease(ctx, req.(*AppendLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_CommitRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).CommitRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_CommitRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).CommitRecord(ctx, req.(*CommitRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_Lock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).Lock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_Lock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).Lock(ctx, req.(*LockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metadata_Unlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServer).Unlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metadata_Unlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServer).Unlock(ctx, req.(*PathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Metadata_ServiceDesc is the grpc.ServiceDesc for Metadata service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Metadata_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "meta.Metadata",
+	HandlerType: (*MetadataServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Mkdir",
+			Handler:    _Metadata_Mkdir_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Metadata_List_Handler,
+		},
+		{
+			MethodName: "Stat",
+			Handler:    _Metadata_Stat_Handler,
+		},
+		{
+			MethodName: "AddFile",
+			Handler:    _Metadata_AddFile_Handler,
+		},
+		{
+			MethodName: "RemoveFile",
+			Handler:    _Metadata_RemoveFile_Handler,
+		},
+		{
+			MethodName: "Rename",
+			Handler:    _Metadata_Rename_Handler,
+		},
+		{
+			MethodName: "BlockReport",
+			Handler:    _Metadata_BlockReport_Handler,
+		},
+		{
+			MethodName: "MissingReplicas",
+			Handler:    _Metadata_MissingReplicas_Handler,
+		},
+		{
+			MethodName: "GetDefaults",
+			Handler:    _Metadata_GetDefaults_Handler,
+		},
+		{
+			MethodName: "SetDefaults",
+			Handler:    _Metadata_SetDefaults_Handler,
+		},
+		{
+			MethodName: "BeginUpload",
+			Handler:    _Metadata_BeginUpload_Handler,
+		},
+		{
+			MethodName: "AddChunk",
+			Handler:    _Metadata_AddChunk_Handler,
+		},
+		{
+			MethodName: "CommitUpload",
+			Handler:    _Metadata_CommitUpload_Handler,
+		},
+		{
+			MethodName: "AbortUpload",
+			Handler:    _Metadata_AbortUpload_Handler,
+		},
+		{
+			MethodName: "ListUploads",
+			Handler:    _Metadata_ListUploads_Handler,
+		},
+		{
+			MethodName: "ReferencedChunks",
+			Handler:    _Metadata_ReferencedChunks_Handler,
+		},
+		{
+			MethodName: "GetUpload",
+			Handler:    _Metadata_GetUpload_Handler,
+		},
+		{
+			MethodName: "CommitPart",
+			Handler:    _Metadata_CommitPart_Handler,
+		},
+		{
+			MethodName: "CompleteMultipartUpload",
+			Handler:    _Metadata_CompleteMultipartUpload_Handler,
+		},
+		{
+			MethodName: "GrantAppendLease",
+			Handler:    _Metadata_GrantAppendLease_Handler,
+		},
+		{
+			MethodName: "CommitRecord",
+			Handler:    _Metadata_CommitRecord_Handler,
+		},
+		{
+			MethodName: "Lock",
+			Handler:    _Metadata_Lock_Handler,
+		},
+		{
+			MethodName: "Unlock",
+			Handler:    _Metadata_Unlock_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Walk",
+			Handler:       _Metadata_Walk_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/meta.proto",
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"log"
+	"math"
 	"net"
+	"time"
 
+	"grpc-distributed-fs/metadata"
 	pb "grpc-distributed-fs/proto/fs"
 	"grpc-distributed-fs/storage"
 
@@ -13,7 +18,21 @@ import (
 
 type serverImpl struct {
 	pb.UnimplementedFileSystemServer
-	db *storage.FileDB
+	db     *storage.FileDB
+	id     string
+	labels map[string]string
+	peers  *peerPool
+	chunks chunkLocks // 记录追加时按分片串行化，并缓存追加分片的大小和校验值
+}
+
+// 单条消息的最大字节数：读写整个分片和记录追加的填充都在一条消息中，需大于最大分片大小
+const maxChunkMessage = math.MaxInt32
+
+// 提供存储服务的 gRPC 服务器，放宽默认 4 MiB 的消息大小上限
+func newGRPCServer(s *serverImpl) *grpc.Server {
+	srv := grpc.NewServer(grpc.MaxRecvMsgSize(maxChunkMessage), grpc.MaxSendMsgSize(maxChunkMessage))
+	pb.RegisterFileSystemServer(srv, s)
+	return srv
 }
 
 // 创建新文件（包括目录路径）
@@ -23,6 +42,7 @@ func (s *serverImpl) WriteFile(ctx context.Context, req *pb.WriteRequest) (*pb.W
 		log.Printf("Error inserting file: %v", err)
 		return nil, err
 	}
+	s.chunks.forget(req.Filename)
 	return &pb.WriteResponse{}, nil
 }
 
@@ -33,6 +53,9 @@ func (s *serverImpl) ReadFile(ctx context.Context, req *pb.ReadRequest) (*pb.Rea
 		log.Printf("Error reading file: %v", err)
 		return nil, err
 	}
+	if req.Length > 0 && req.Length < int64(len(data)) {
+		data = data[:req.Length]
+	}
 	return &pb.ReadResponse{Data: data}, nil
 }
 
@@ -43,23 +66,89 @@ func (s *serverImpl) DeleteFile(ctx context.Context, req *pb.DeleteRequest) (*pb
 		log.Printf("Error deleting file: %v", err)
 		return nil, err
 	}
+	s.chunks.forget(req.Filename)
 	return &pb.DeleteResponse{}, nil
 }
 
+// 列出节点上的所有文件
+func (s *serverImpl) ListFiles(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	files, err := s.db.ListFiles("/") // 假设路径是根目录
+	if err != nil {
+		log.Printf("Error listing files: %v", err)
+		return nil, err
+	}
+	resp := &pb.ListResponse{}
+	for _, file := range files {
+		info := &pb.FileInfo{Name: file.Name, Size: file.Size}
+		if !file.ModTime.IsZero() {
+			info.ModTime = file.ModTime.Unix()
+		}
+		resp.Files = append(resp.Files, file.Name)
+		resp.Entries = append(resp.Entries, info)
+	}
+	return resp, nil
+}
+
+// 返回文件大小和校验值，供客户端判断分片是否已完整写入
+func (s *serverImpl) StatFile(ctx context.Context, req *pb.StatRequest) (*pb.StatResponse, error) {
+	data, err := s.db.ReadFile(req.Filename, "/") // 假设路径是根目录
+	if errors.Is(err, storage.ErrFileNotFound) {
+		return &pb.StatResponse{Exists: false}, nil
+	}
+	if err != nil {
+		log.Printf("Error reading file: %v", err)
+		return nil, err
+	}
+	return &pb.StatResponse{Exists: true, Size: int64(len(data)), Checksum: metadata.Checksum(data)}, nil
+}
+
+// 返回节点身份与拓扑标签
+func (s *serverImpl) NodeInfo(ctx context.Context, req *pb.NodeInfoRequest) (*pb.NodeInfoResponse, error) {
+	return &pb.NodeInfoResponse{Id: s.id, Labels: s.labels}, nil
+}
+
 func main() {
+	port := flag.String("port", "50053", "listen port")
+	dataDir := flag.String("data", "data", "data directory")
+	id := flag.String("id", "", "node id")
+	host := flag.String("host", "", "host label")
+	rack := flag.String("rack", "", "rack label")
+	zone := flag.String("zone", "", "zone label")
+	metaAddr := flag.String("meta", ":50050", "metadata service address for block reports")
+	reportInterval := flag.Duration("report-interval", time.Minute, "block report interval")
+	advertise := flag.String("advertise", "", "address the metadata service uses to reach this node (default :<port>)")
+	flag.Parse()
+	if *advertise == "" {
+		*advertise = ":" + *port
+	}
+
+	// 拓扑标签，未设置的标签不上报
+	labels := make(map[string]string)
+	for key, value := range map[string]string{"host": *host, "rack": *rack, "zone": *zone} {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+
 	// 初始化数据库
-	db := storage.NewFileDB("data")
+	db := storage.NewFileDB(*dataDir)
 	defer db.Close()
 
+	// 块报告需要节点 ID 与客户端配置中的一致
+	if *id != "" && *metaAddr != "" {
+		startBlockReports(*metaAddr, *id, *advertise, *reportInterval, db)
+	} else {
+		log.Println("Block reports disabled: -id or -meta not set")
+	}
+
 	// 启动 gRPC 服务
-	lis, err := net.Listen("tcp", ":50053")
+	lis, err := net.Listen("tcp", ":"+*port)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
-	grpcServer := grpc.NewServer()
-	pb.RegisterFileSystemServer(grpcServer, &serverImpl{db: db})
+	grpcServer := newGRPCServer(&serverImpl{db: db, id: *id, labels: labels, peers: newPeerPool()})
 
-	log.Println("Server is running on port 50053")
+	log.Printf("Server is running on port %s", *port)
 	if err := grpcServer.Serve(lis); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
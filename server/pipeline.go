@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// 到其他存储节点的 gRPC 连接，按地址复用
+type peerPool struct {
+	mu    sync.Mutex
+	peers map[string]pb.FileSystemClient
+}
+
+func newPeerPool() *peerPool {
+	return &peerPool{peers: make(map[string]pb.FileSystemClient)}
+}
+
+func (p *peerPool) get(addr string) (pb.FileSystemClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if client, exists := p.peers[addr]; exists {
+		return client, nil
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxChunkMessage), grpc.MaxCallSendMsgSize(maxChunkMessage)))
+	if err != nil {
+		return nil, err
+	}
+	client := pb.NewFileSystemClient(conn)
+	p.peers[addr] = client
+	return client, nil
+}
+
+// 流水线写入：边接收边转发给下一个副本节点，校验并本地写入后向上游确认
+// 下游失败不影响本地写入，只是不出现在确认列表中，由客户端按写入法定数判断是否成功
+// 收到的数据与校验值不一致时返回 DataLoss，不写入，也不再结束到下游的流，下游同样不写入
+func (s *serverImpl) PipelineWrite(stream pb.FileSystem_PipelineWriteServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	filename := first.Filename
+	if filename == "" {
+		return errors.New("pipeline write: missing filename")
+	}
+
+	// 建立到下一个节点的流，剩余的下游地址继续向后传递
+	// 下一个节点不可达时跳过它，由之后的节点继续流水线
+	var next pb.FileSystem_PipelineWriteClient
+	downstream := first.Downstream
+	for next == nil && len(downstream) > 0 {
+		next, err = s.openPipeline(stream.Context(), downstream)
+		if err == nil {
+			err = next.Send(&pb.PipelineWriteRequest{Filename: filename, Downstream: downstream[1:], Data: first.Data, Checksum: first.Checksum})
+		}
+		if err != nil {
+			log.Printf("Pipeline write %s: forward to %s: %v", filename, downstream[0], err)
+			next = nil
+			downstream = downstream[1:]
+		}
+	}
+
+	data := append([]byte(nil), first.Data...)
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		data = append(data, req.Data...)
+		if next != nil {
+			if err := next.Send(&pb.PipelineWriteRequest{Data: req.Data}); err != nil {
+				log.Printf("Pipeline write %s: forward to %s: %v", filename, downstream[0], err)
+				next = nil
+			}
+		}
+	}
+
+	if first.Checksum != "" && metadata.Checksum(data) != first.Checksum {
+		log.Printf("Pipeline write %s: checksum mismatch", filename)
+		return status.Errorf(codes.DataLoss, "pipeline write %s: checksum mismatch", filename)
+	}
+	if err := s.db.WriteFile(filename, "/", data); err != nil {
+		log.Printf("Error inserting file: %v", err)
+		return err
+	}
+	s.chunks.forget(filename)
+
+	var acked []string
+	if next != nil {
+		resp, err := next.CloseAndRecv()
+		if err != nil {
+			log.Printf("Pipeline write %s: downstream %s: %v", filename, downstream[0], err)
+		} else {
+			acked = append([]string{downstream[0]}, resp.Acked...)
+		}
+	}
+	return stream.SendAndClose(&pb.PipelineWriteResponse{Acked: acked})
+}
+
+// 打开到 downstream[0] 的流水线写入流
+func (s *serverImpl) openPipeline(ctx context.Context, downstream []string) (pb.FileSystem_PipelineWriteClient, error) {
+	peer, err := s.peers.get(downstream[0])
+	if err != nil {
+		return nil, err
+	}
+	return peer.PipelineWrite(ctx)
+}
+
+// 流水线写入时每条消息携带的最大数据量
+const pipelinePieceSize = 64 * 1024
+
+// 将本节点上的分片沿流水线复制到 targets，用于元数据服务修复缺失的副本
+func (s *serverImpl) Replicate(ctx context.Context, req *pb.ReplicateRequest) (*pb.ReplicateResponse, error) {
+	if len(req.Targets) == 0 {
+		return &pb.ReplicateResponse{}, nil
+	}
+	data, err := s.db.ReadFile(req.Filename, "/") // 假设路径是根目录
+	if err != nil {
+		return nil, fmt.Errorf("replicate %s: %w", req.Filename, err)
+	}
+
+	stream, err := s.openPipeline(ctx, req.Targets)
+	if err != nil {
+		return nil, fmt.Errorf("replicate %s: connect %s: %w", req.Filename, req.Targets[0], err)
+	}
+	msg := &pb.PipelineWriteRequest{Filename: req.Filename, Downstream: req.Targets[1:], Checksum: metadata.Checksum(data)}
+	for offset := 0; ; offset += pipelinePieceSize {
+		msg.Data = data[offset:min(offset+pipelinePieceSize, len(data))]
+		if err := stream.Send(msg); err != nil {
+			break // 真正的错误由 CloseAndRecv 返回
+		}
+		if offset+pipelinePieceSize >= len(data) {
+			break
+		}
+		msg = &pb.PipelineWriteRequest{}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, fmt.Errorf("replicate %s: %s: %w", req.Filename, req.Targets[0], err)
+	}
+	log.Printf("Replicated %s to %v", req.Filename, append([]string{req.Targets[0]}, resp.Acked...))
+	return &pb.ReplicateResponse{Acked: append([]string{req.Targets[0]}, resp.Acked...)}, nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"slices"
+	"testing"
+
+	"grpc-distributed-fs/metadata"
+	pb "grpc-distributed-fs/proto/fs"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 经流水线写入分片，每条消息至多 pipelinePieceSize 字节，返回确认写入的下游节点地址
+func pipelineWrite(t *testing.T, client pb.FileSystemClient, filename string, downstream []string, data []byte, checksum string) ([]string, error) {
+	t.Helper()
+	stream, err := client.PipelineWrite(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &pb.PipelineWriteRequest{Filename: filename, Downstream: downstream, Checksum: checksum}
+	for offset := 0; offset < len(data); offset += pipelinePieceSize {
+		msg.Data = data[offset:min(offset+pipelinePieceSize, len(data))]
+		if err := stream.Send(msg); err != nil {
+			break
+		}
+		msg = &pb.PipelineWriteRequest{}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Acked, nil
+}
+
+// 没有节点监听的地址
+func deadAddr(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	return addr
+}
+
+// 在转发给 target 的流水线写入中篡改数据的中间人，模拟两个节点之间的传输错误
+type corruptingProxy struct {
+	pb.UnimplementedFileSystemServer
+	target pb.FileSystemClient
+}
+
+func (p *corruptingProxy) PipelineWrite(stream pb.FileSystem_PipelineWriteServer) error {
+	next, err := p.target.PipelineWrite(stream.Context())
+	if err != nil {
+		return err
+	}
+	for first := true; ; first = false {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if first {
+			req.Data = append([]byte(nil), req.Data...)
+			req.Data[0] ^= 0xff
+		}
+		if err := next.Send(req); err != nil {
+			break
+		}
+	}
+	resp, err := next.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(resp)
+}
+
+func startCorruptingProxy(t *testing.T, target string) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterFileSystemServer(srv, &corruptingProxy{target: dialTestNode(t, target)})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestPipelineWrite(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), pipelinePieceSize/8+3) // 三条消息
+	checksum := metadata.Checksum(data)
+	tests := []struct {
+		name       string
+		downstream []string // 下游节点："b"、"c" 为正常节点，"dead" 不可达，"corrupt" 篡改数据后转发给 b
+		checksum   string
+		wantCode   codes.Code
+		wantAcked  []string
+		wantStored []string // 写入了分片的节点
+	}{
+		{name: "forward to every replica", downstream: []string{"b", "c"}, checksum: checksum, wantAcked: []string{"b", "c"}, wantStored: []string{"a", "b", "c"}},
+		{name: "without checksum", downstream: []string{"b", "c"}, wantAcked: []string{"b", "c"}, wantStored: []string{"a", "b", "c"}},
+		{name: "skip unreachable next hop", downstream: []string{"dead", "c"}, checksum: checksum, wantAcked: []string{"c"}, wantStored: []string{"a", "c"}},
+		{name: "unreachable last hop", downstream: []string{"b", "dead"}, checksum: checksum, wantAcked: []string{"b"}, wantStored: []string{"a", "b"}},
+		{name: "corrupted mid-chain", downstream: []string{"corrupt", "c"}, checksum: checksum, wantAcked: nil, wantStored: []string{"a"}},
+		{name: "checksum mismatch at head", downstream: []string{"b"}, checksum: metadata.Checksum([]byte("other")), wantCode: codes.DataLoss},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := make(map[string]*serverImpl)
+			addrs := make(map[string]string)
+			for _, name := range []string{"a", "b", "c"} {
+				nodes[name], addrs[name] = startTestNode(t, name)
+			}
+			addrs["dead"] = deadAddr(t)
+			addrs["corrupt"] = startCorruptingProxy(t, addrs["b"])
+			names := make(map[string]string)
+			var downstream []string
+			for _, name := range tt.downstream {
+				downstream = append(downstream, addrs[name])
+				names[addrs[name]] = name
+			}
+			names[addrs["corrupt"]] = "corrupt"
+
+			acked, err := pipelineWrite(t, dialTestNode(t, addrs["a"]), "c1", downstream, data, tt.checksum)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("PipelineWrite = %v, want %v", err, tt.wantCode)
+			}
+			var gotAcked []string
+			for _, addr := range acked {
+				gotAcked = append(gotAcked, names[addr])
+			}
+			if !slices.Equal(gotAcked, tt.wantAcked) {
+				t.Errorf("acked %v, want %v", gotAcked, tt.wantAcked)
+			}
+			for _, name := range []string{"a", "b", "c"} {
+				got, err := nodes[name].db.ReadFile("c1", "/")
+				if want := slices.Contains(tt.wantStored, name); (err == nil) != want {
+					t.Errorf("node %s stored = %v, want %v", name, err == nil, want)
+				} else if err == nil && !bytes.Equal(got, data) {
+					t.Errorf("node %s stored %d bytes that differ from the %d written", name, len(got), len(data))
+				}
+			}
+		})
+	}
+}
+
+// 复制时携带校验值，沿流水线写入目标节点
+func TestReplicate(t *testing.T) {
+	source, sourceAddr := startTestNode(t, "source")
+	b, bAddr := startTestNode(t, "b")
+	c, cAddr := startTestNode(t, "c")
+	data := bytes.Repeat([]byte("x"), 2*pipelinePieceSize+1)
+	if err := source.db.WriteFile("c1", "/", data); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := dialTestNode(t, sourceAddr).Replicate(context.Background(), &pb.ReplicateRequest{Filename: "c1", Targets: []string{bAddr, cAddr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{bAddr, cAddr}; !slices.Equal(resp.Acked, want) {
+		t.Errorf("acked %v, want %v", resp.Acked, want)
+	}
+	for name, node := range map[string]*serverImpl{"b": b, "c": c} {
+		if got, err := node.db.ReadFile("c1", "/"); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("node %s: %d bytes, %v", name, len(got), err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"log"
+	"sync"
+	"time"
+
+	pb "grpc-distributed-fs/proto/fs"
+	"grpc-distributed-fs/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 按分片加锁，同一分片上的记录追加依次执行
+type chunkLocks struct {
+	mu    sync.Mutex
+	locks map[string]*chunkState
+}
+
+// 记录追加的分片在本节点上的大小和校验值，首次追加时从存储读取，之后随追加更新，追加时不必读取整个分片
+type chunkState struct {
+	sync.Mutex
+	loaded bool
+	size   int64
+	sum    hash.Hash // 已有数据的 SHA-256 状态
+}
+
+// 锁定分片，返回的状态在 Unlock 之前归调用方使用
+func (l *chunkLocks) lock(filename string) *chunkState {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*chunkState)
+	}
+	st, exists := l.locks[filename]
+	if !exists {
+		st = &chunkState{}
+		l.locks[filename] = st
+	}
+	l.mu.Unlock()
+	st.Lock()
+	return st
+}
+
+// 分片被整体写入或删除后，丢弃缓存的大小和校验值，下次追加时重新读取
+func (l *chunkLocks) forget(filename string) {
+	l.mu.Lock()
+	st, exists := l.locks[filename]
+	l.mu.Unlock()
+	if exists {
+		st.Lock()
+		st.loaded = false
+		st.Unlock()
+	}
+}
+
+// 读取分片的当前大小和校验值，调用方需锁定分片
+func (s *serverImpl) loadChunk(filename string, st *chunkState) error {
+	if st.loaded {
+		return nil
+	}
+	data, err := s.db.ReadFile(filename, "/") // 假设路径是根目录
+	if err != nil && !errors.Is(err, storage.ErrFileNotFound) {
+		return err
+	}
+	st.size, st.sum, st.loaded = int64(len(data)), sha256.New(), true
+	st.sum.Write(data)
+	return nil
+}
+
+// 在分片末尾追加 data，只写入新增的字节，调用方需锁定分片并已读取其状态
+func (s *serverImpl) appendChunk(filename string, st *chunkState, data []byte) error {
+	if err := s.db.AppendFile(filename, "/", st.size, data); err != nil {
+		st.loaded = false // 写入可能已部分生效
+		return err
+	}
+	st.size += int64(len(data))
+	st.sum.Write(data)
+	return nil
+}
+
+// 作为主副本追加记录：偏移为本地分片的当前大小，先写入所有其余副本，再写入本地
+// 记录放不下时将分片填充到上限并返回 full，客户端随后换用新分片，记录因此不会跨越分片
+// 其余副本写入失败时在 failed_secondary 中返回其地址，本地分片不变，同一偏移可以重试
+func (s *serverImpl) AppendRecord(ctx context.Context, req *pb.AppendRecordRequest) (*pb.AppendRecordResponse, error) {
+	if time.Now().UnixNano() > req.LeaseExpiresAt {
+		return nil, status.Error(codes.FailedPrecondition, "append lease expired")
+	}
+	if int64(len(req.Data)) > req.MaxSize {
+		return nil, status.Errorf(codes.InvalidArgument, "record of %d bytes exceeds chunk size %d", len(req.Data), req.MaxSize)
+	}
+	st := s.chunks.lock(req.Filename)
+	defer st.Unlock()
+
+	if err := s.loadChunk(req.Filename, st); err != nil {
+		return nil, err
+	}
+	offset := st.size
+	record, full := req.Data, false
+	if offset+int64(len(record)) > req.MaxSize {
+		record, full = make([]byte, max(req.MaxSize-offset, 0)), true
+	}
+	if len(record) > 0 {
+		for _, addr := range req.Secondaries {
+			peer, err := s.peers.get(addr)
+			if err == nil {
+				_, err = peer.WriteRecord(ctx, &pb.WriteRecordRequest{Filename: req.Filename, Offset: offset, Data: record})
+			}
+			if err != nil {
+				log.Printf("Append record %s: secondary %s: %v", req.Filename, addr, err)
+				return &pb.AppendRecordResponse{FailedSecondary: addr}, nil
+			}
+		}
+		if err := s.appendChunk(req.Filename, st, record); err != nil {
+			return nil, err
+		}
+	}
+	return &pb.AppendRecordResponse{Offset: offset, Size: st.size, Checksum: hex.EncodeToString(st.sum.Sum(nil)), Full: full}, nil
+}
+
+// 作为其余副本在 offset 处写入记录，丢弃之前失败的追加留下的数据
+// 通常 offset 即为本地分片的大小，只追加新增的字节；重试时才需要截断并重写分片
+func (s *serverImpl) WriteRecord(ctx context.Context, req *pb.WriteRecordRequest) (*pb.WriteResponse, error) {
+	st := s.chunks.lock(req.Filename)
+	defer st.Unlock()
+
+	if err := s.loadChunk(req.Filename, st); err != nil {
+		return nil, err
+	}
+	switch {
+	case req.Offset > st.size:
+		return nil, status.Errorf(codes.FailedPrecondition, "%s has %d bytes, cannot write at %d", req.Filename, st.size, req.Offset)
+	case req.Offset == st.size:
+		if err := s.appendChunk(req.Filename, st, req.Data); err != nil {
+			return nil, err
+		}
+	default:
+		data, err := s.db.ReadFile(req.Filename, "/") // 假设路径是根目录
+		if err != nil {
+			return nil, err
+		}
+		st.loaded = false
+		if err := s.db.WriteFile(req.Filename, "/", append(data[:req.Offset], req.Data...)); err != nil {
+			return nil, err
+		}
+	}
+	return &pb.WriteResponse{}, nil
+}
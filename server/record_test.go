@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "grpc-distributed-fs/proto/fs"
+	"grpc-distributed-fs/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// 启动使用临时数据目录的存储节点，返回其服务实现和地址
+func startTestNode(t *testing.T, id string) (*serverImpl, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := storage.NewFileDB(t.TempDir())
+	s := &serverImpl{db: db, id: id, peers: newPeerPool()}
+	srv := newGRPCServer(s)
+	go srv.Serve(lis)
+	t.Cleanup(func() {
+		srv.Stop()
+		db.Close()
+	})
+	return s, lis.Addr().String()
+}
+
+// 连接存储节点的客户端，与 dfs 客户端一样接收整个分片
+func dialTestNode(t *testing.T, addr string) pb.FileSystemClient {
+	t.Helper()
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxChunkMessage)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewFileSystemClient(conn)
+}
+
+// 超过 gRPC 默认 4 MiB 上限的记录和填充都能写入主副本并转发给其余副本
+func TestAppendRecordLargerThanDefaultMessage(t *testing.T) {
+	const mib = 1 << 20
+	_, primaryAddr := startTestNode(t, "primary")
+	_, secondaryAddr := startTestNode(t, "secondary")
+	primary := dialTestNode(t, primaryAddr)
+	secondary := dialTestNode(t, secondaryAddr)
+	ctx := context.Background()
+
+	appendRecord := func(data []byte) *pb.AppendRecordResponse {
+		t.Helper()
+		resp, err := primary.AppendRecord(ctx, &pb.AppendRecordRequest{
+			Filename:       "c",
+			Data:           data,
+			Secondaries:    []string{secondaryAddr},
+			MaxSize:        10 * mib,
+			LeaseExpiresAt: time.Now().Add(time.Minute).UnixNano(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.FailedSecondary != "" {
+			t.Fatalf("secondary %s failed", resp.FailedSecondary)
+		}
+		return resp
+	}
+	record := bytes.Repeat([]byte("r"), 5*mib)
+	if resp := appendRecord(record); resp.Offset != 0 || resp.Size != 5*mib || resp.Full {
+		t.Fatalf("first record: offset %d, size %d, full %v", resp.Offset, resp.Size, resp.Full)
+	}
+	// 放不下的记录使分片以 5 MiB 的零字节填充到上限
+	if resp := appendRecord(bytes.Repeat([]byte("s"), 6*mib)); resp.Size != 10*mib || !resp.Full {
+		t.Fatalf("second record: size %d, full %v", resp.Size, resp.Full)
+	}
+
+	want := append(record, make([]byte, 5*mib)...)
+	for name, node := range map[string]pb.FileSystemClient{"primary": primary, "secondary": secondary} {
+		resp, err := node.ReadFile(ctx, &pb.ReadRequest{Filename: "c"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(resp.Data, want) {
+			t.Errorf("%s holds %d bytes, want the record and padding", name, len(resp.Data))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	metapb "grpc-distributed-fs/proto/meta"
+	"grpc-distributed-fs/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// 启动时及之后每隔 interval 向元数据服务发送一次块报告
+func startBlockReports(metaAddr, nodeID, addr string, interval time.Duration, db *storage.FileDB) {
+	conn, err := grpc.NewClient(metaAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Printf("Block reports disabled: %v", err)
+		return
+	}
+	meta := metapb.NewMetadataClient(conn)
+
+	go func() {
+		for {
+			if err := sendBlockReport(meta, nodeID, addr, db); err != nil {
+				log.Printf("Failed to send block report: %v", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// 发送节点上的完整分片清单
+func sendBlockReport(meta metapb.MetadataClient, nodeID, addr string, db *storage.FileDB) error {
+	generatedAt := time.Now()
+	files, err := db.ListFiles("/") // 假设路径是根目录
+	if err != nil {
+		return err
+	}
+	req := &metapb.BlockReportRequest{NodeId: nodeID, Addr: addr, GeneratedAt: generatedAt.UnixNano()}
+	for _, file := range files {
+		req.ChunkIds = append(req.ChunkIds, file.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := meta.BlockReport(ctx, req)
+	if err != nil {
+		return err
+	}
+	log.Printf("Block report sent: %d chunks, %d added, %d removed, %d unknown",
+		len(req.ChunkIds), resp.Added, resp.Removed, resp.Unknown)
+	return nil
+}
@@ -1,15 +1,45 @@
 package storage
 
 import (
+	"encoding/binary"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/dgraph-io/badger/v3"
 )
 
+// 文件不存在
+var ErrFileNotFound = badger.ErrKeyNotFound
+
+// 修改时间单独存放在带此前缀的键中，文件内容格式保持不变
+const mtimePrefix = "mtime:"
+
+// 追加写入的数据段存放在带此前缀的键中，键以数据段在文件中的偏移结尾，读取时按偏移依次拼接到文件内容之后
+// 追加只写入新增的字节，不重写整个文件
+const appendPrefix = "append:"
+
+// 文件 filePath 的数据段键的前缀，以 \x00 分隔，避免与其他文件的键混淆
+func segmentPrefix(filePath string) string {
+	return appendPrefix + filePath + "\x00"
+}
+
+func segmentKey(filePath string, offset int64) []byte {
+	return []byte(fmt.Sprintf("%s%016x", segmentPrefix(filePath), offset))
+}
+
 type FileDB struct {
 	db *badger.DB
 }
 
+// 文件信息
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time // 没有记录修改时间的旧文件为零值
+}
+
 // 初始化数据库
 func NewFileDB(dbPath string) *FileDB {
 	opts := badger.DefaultOptions(dbPath).WithLoggingLevel(badger.ERROR)
@@ -24,11 +54,41 @@ func NewFileDB(dbPath string) *FileDB {
 func (fdb *FileDB) WriteFile(filename, parentPath string, data []byte) error {
 	// 生成完整路径作为键
 	filePath := parentPath + "/" + filename
+	mtime := make([]byte, 8)
+	binary.BigEndian.PutUint64(mtime, uint64(time.Now().UnixNano()))
+	// 与旧的数据段在同一事务中替换，避免中途失败留下新数据之后仍拼接旧数据段的文件
 	return fdb.db.Update(func(txn *badger.Txn) error {
+		if err := deleteSegments(txn, filePath); err != nil {
+			return err
+		}
+		if err := txn.Set([]byte(mtimePrefix+filePath), mtime); err != nil {
+			return err
+		}
 		return txn.Set([]byte(filePath), data)
 	})
 }
 
+// 在文件末尾追加 data，只写入新增的字节；offset 为追加前的文件大小，由调用方维护
+// 文件不存在时创建空文件后追加
+func (fdb *FileDB) AppendFile(filename, parentPath string, offset int64, data []byte) error {
+	filePath := parentPath + "/" + filename
+	mtime := make([]byte, 8)
+	binary.BigEndian.PutUint64(mtime, uint64(time.Now().UnixNano()))
+	return fdb.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get([]byte(filePath)); err == badger.ErrKeyNotFound {
+			if err := txn.Set([]byte(filePath), nil); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+		if err := txn.Set([]byte(mtimePrefix+filePath), mtime); err != nil {
+			return err
+		}
+		return txn.Set(segmentKey(filePath, offset), data)
+	})
+}
+
 // 读取文件，使用完整路径作为键
 func (fdb *FileDB) ReadFile(filename, parentPath string) ([]byte, error) {
 	filePath := parentPath + "/" + filename
@@ -38,8 +98,23 @@ func (fdb *FileDB) ReadFile(filename, parentPath string) ([]byte, error) {
 		if err != nil {
 			return err
 		}
-		data, err = item.ValueCopy(nil)
-		return err
+		if data, err = item.ValueCopy(nil); err != nil {
+			return err
+		}
+		// 依次拼接追加的数据段
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(segmentPrefix(filePath))
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := it.Item().Value(func(val []byte) error {
+				data = append(data, val...)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	return data, err
 }
@@ -48,10 +123,85 @@ func (fdb *FileDB) ReadFile(filename, parentPath string) ([]byte, error) {
 func (fdb *FileDB) DeleteFile(filename, parentPath string) error {
 	filePath := parentPath + "/" + filename
 	return fdb.db.Update(func(txn *badger.Txn) error {
+		if err := deleteSegments(txn, filePath); err != nil {
+			return err
+		}
+		if err := txn.Delete([]byte(mtimePrefix + filePath)); err != nil {
+			return err
+		}
 		return txn.Delete([]byte(filePath))
 	})
 }
 
+// 在事务 txn 中删除文件追加的全部数据段
+func deleteSegments(txn *badger.Txn, filePath string) error {
+	var keys [][]byte
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = []byte(segmentPrefix(filePath))
+	it := txn.NewIterator(opts)
+	for it.Rewind(); it.Valid(); it.Next() {
+		keys = append(keys, it.Item().KeyCopy(nil))
+	}
+	it.Close()
+	for _, key := range keys {
+		if err := txn.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 列出目录下的所有文件
+func (fdb *FileDB) ListFiles(parentPath string) ([]FileInfo, error) {
+	prefix := parentPath + "/"
+	var files []FileInfo
+	err := fdb.db.View(func(txn *badger.Txn) error {
+		// 追加的数据段计入文件大小
+		appended := make(map[string]int64)
+		segOpts := badger.DefaultIteratorOptions
+		segOpts.PrefetchValues = false
+		segOpts.Prefix = []byte(appendPrefix + prefix)
+		seg := txn.NewIterator(segOpts)
+		for seg.Rewind(); seg.Valid(); seg.Next() {
+			key := strings.TrimPrefix(string(seg.Item().Key()), appendPrefix)
+			if i := strings.LastIndexByte(key, 0); i >= 0 {
+				appended[key[:i]] += seg.Item().ValueSize()
+			}
+		}
+		seg.Close()
+
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = []byte(prefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			key := string(item.Key())
+			info := FileInfo{
+				Name: strings.TrimPrefix(key, prefix),
+				Size: item.ValueSize() + appended[key],
+			}
+			mtime, err := txn.Get([]byte(mtimePrefix + key))
+			if err == nil {
+				err = mtime.Value(func(val []byte) error {
+					if len(val) == 8 {
+						info.ModTime = time.Unix(0, int64(binary.BigEndian.Uint64(val)))
+					}
+					return nil
+				})
+			}
+			if err != nil && err != badger.ErrKeyNotFound {
+				return err
+			}
+			files = append(files, info)
+		}
+		return nil
+	})
+	return files, err
+}
+
 // 关闭数据库
 func (fdb *FileDB) Close() {
 	fdb.db.Close()
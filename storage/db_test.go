@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAppendFile(t *testing.T) {
+	db := NewFileDB(t.TempDir())
+	defer db.Close()
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := db.ReadFile(name, "/")
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return string(data)
+	}
+	size := func(name string) int64 {
+		t.Helper()
+		files, err := db.ListFiles("/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			if file.Name == name {
+				return file.Size
+			}
+		}
+		t.Fatalf("%s not listed", name)
+		return 0
+	}
+
+	// 不存在的文件追加后创建，数据段按偏移拼接
+	var offset int64
+	for _, record := range []string{"first,", "second,", "third"} {
+		if err := db.AppendFile("log", "/", offset, []byte(record)); err != nil {
+			t.Fatal(err)
+		}
+		offset += int64(len(record))
+	}
+	if got, want := read("log"), "first,second,third"; got != want {
+		t.Fatalf("after append: %q, want %q", got, want)
+	}
+	if got := size("log"); got != offset {
+		t.Fatalf("listed size %d, want %d", got, offset)
+	}
+
+	// 整体写入替换之前追加的数据段
+	if err := db.WriteFile("log", "/", []byte("base")); err != nil {
+		t.Fatal(err)
+	}
+	if got := read("log"); got != "base" {
+		t.Fatalf("after rewrite: %q, want %q", got, "base")
+	}
+	if err := db.AppendFile("log", "/", 4, []byte("+tail")); err != nil {
+		t.Fatal(err)
+	}
+	if got := read("log"); got != "base+tail" {
+		t.Fatalf("after append to rewritten file: %q, want %q", got, "base+tail")
+	}
+
+	// 名称为前缀的其他文件不受影响
+	if err := db.AppendFile("lo", "/", 0, []byte("other")); err != nil {
+		t.Fatal(err)
+	}
+	if got := read("log"); got != "base+tail" {
+		t.Fatalf("after appending to another file: %q", got)
+	}
+
+	// 删除文件同时删除数据段
+	if err := db.DeleteFile("log", "/"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ReadFile("log", "/"); !errors.Is(err, ErrFileNotFound) {
+		t.Fatalf("read after delete: %v, want ErrFileNotFound", err)
+	}
+	if err := db.AppendFile("log", "/", 0, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if got := read("log"); got != "new" {
+		t.Fatalf("after recreate: %q, want %q", got, "new")
+	}
+}